@@ -24,6 +24,8 @@ export interface Track {
   title: string
   artist: string
   album?: string
+  source_id?: string
+  duration_ms?: number
   match_key: string
 }
 
@@ -36,6 +38,13 @@ export interface Playlist {
 export interface SpotifyPlaylist {
   name: string
   id: string
+  url?: string
+  track_count?: number
+  owner_id?: string
+  owner_name?: string
+  image_url?: string
+  public?: boolean
+  collaborative?: boolean
 }
 
 function App() {
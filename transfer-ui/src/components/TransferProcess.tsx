@@ -1,4 +1,4 @@
-import { useState, useEffect } from 'react'
+import { useState, useEffect, useRef } from 'react'
 import {
   VStack,
   HStack,
@@ -15,9 +15,17 @@ import {
   Badge,
   useToast,
 } from '@chakra-ui/react'
-import { api } from '../services/api'
+import { api, TransferJob } from '../services/api'
 import { Track } from '../App'
 
+// 查询任务进度的间隔
+const POLL_INTERVAL_MS = 1000
+
+const sleep = (ms: number) => new Promise(resolve => setTimeout(resolve, ms))
+
+// 任务本身失败或被取消，错误信息来自后端
+class JobFailedError extends Error {}
+
 interface TransferProcessProps {
   selectedTracks: Track[]
   spotifyPlaylistId: string
@@ -38,35 +46,56 @@ function TransferProcess({
   const [isTransferring, setIsTransferring] = useState(false)
   const [transferComplete, setTransferComplete] = useState(false)
   const [error, setError] = useState<string | null>(null)
+  const [job, setJob] = useState<TransferJob | null>(null)
+  const mounted = useRef(true)
   const toast = useToast()
 
+  useEffect(() => {
+    mounted.current = true
+    return () => {
+      mounted.current = false
+    }
+  }, [])
+
+  // 轮询任务直到结束，进度按已处理的歌曲数计算
+  const waitForJob = async (jobId: string): Promise<TransferJob> => {
+    for (;;) {
+      const current = await api.getTransferJob(jobId)
+      if (!mounted.current) {
+        return current
+      }
+      setJob(current)
+      const processed = current.success_count + current.failed_count + current.review_count + current.present_count
+      setProgress(current.total_tracks > 0 ? (processed / current.total_tracks) * 100 : 0)
+      if (current.status !== 'pending' && current.status !== 'running') {
+        return current
+      }
+      await sleep(POLL_INTERVAL_MS)
+    }
+  }
+
   const startTransfer = async () => {
     setIsTransferring(true)
     setError(null)
     setProgress(0)
 
     try {
-      // 模拟进度更新
-      const interval = setInterval(() => {
-        setProgress(prev => {
-          if (prev >= 90) {
-            clearInterval(interval)
-            return 90
-          }
-          return prev + 10
-        })
-      }, 500)
-
-      // 执行实际的迁移
-      await api.transferTracks(spotifyPlaylistId, selectedTracks)
-      
-      clearInterval(interval)
+      // 迁移在后台任务中进行，关闭页面也不会中断
+      const jobId = await api.createTransferJob(spotifyPlaylistId, selectedTracks)
+      const finished = await waitForJob(jobId)
+      if (!mounted.current) {
+        return
+      }
+      if (finished.status !== 'completed') {
+        throw new JobFailedError(finished.error || `任务${finished.status === 'cancelled' ? '已取消' : '失败'}`)
+      }
+
       setProgress(100)
       setTransferComplete(true)
-      
+
       toast({
         title: '迁移完成！',
-        description: `成功迁移 ${selectedTracks.length} 首歌曲`,
+        description: `成功迁移 ${finished.success_count} 首歌曲，${finished.review_count} 首待确认，${finished.failed_count} 首失败`,
         status: 'success',
         duration: 5000,
         isClosable: true,
@@ -77,7 +106,10 @@ function TransferProcess({
       }, 2000)
       
     } catch (error) {
-      setError('迁移过程中发生错误，请稍后重试')
+      if (!mounted.current) {
+        return
+      }
+      setError(error instanceof JobFailedError ? error.message : '迁移过程中发生错误，请稍后重试')
       toast({
         title: '迁移失败',
         description: '请检查网络连接或稍后重试',
@@ -143,7 +175,9 @@ function TransferProcess({
               <Box>
                 <AlertTitle>迁移成功！</AlertTitle>
                 <AlertDescription>
-                  所有选中的歌曲已成功添加到您的 Spotify 歌单
+                  {job
+                    ? `已添加 ${job.success_count} 首，已存在 ${job.present_count} 首，待确认 ${job.review_count} 首，失败 ${job.failed_count} 首`
+                    : '所有选中的歌曲已成功添加到您的 Spotify 歌单'}
                 </AlertDescription>
               </Box>
             </Alert>
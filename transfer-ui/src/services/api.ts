@@ -2,6 +2,18 @@ import { Playlist, SpotifyPlaylist, Track } from '../App'
 
 const API_BASE = '/api'
 
+// 迁移任务的状态，计数随任务进行不断更新
+export interface TransferJob {
+  id: string
+  status: 'pending' | 'running' | 'completed' | 'failed' | 'cancelled'
+  total_tracks: number
+  success_count: number
+  failed_count: number
+  review_count: number
+  present_count: number
+  error?: string
+}
+
 export const api = {
   // 获取网易云歌单
   fetchNeteasePlaylist: async (playlistId: string): Promise<Playlist> => {
@@ -44,34 +56,55 @@ export const api = {
   },
 
   // 获取 Spotify 用户歌单 (不再需要手动传递认证信息)
+  // 后端按游标分页，这里取完所有可编辑的歌单
   fetchSpotifyPlaylists: async (): Promise<SpotifyPlaylist[]> => {
-    const response = await fetch(`${API_BASE}/spotify/playlists`, {
-      credentials: 'include' // 包含 cookies
-    })
-    if (!response.ok) {
-      throw new Error('Failed to fetch Spotify playlists')
-    }
-    return response.json()
+    const playlists: SpotifyPlaylist[] = []
+    let cursor = ''
+    do {
+      const query = cursor ? `?cursor=${encodeURIComponent(cursor)}` : ''
+      const response = await fetch(`${API_BASE}/spotify/playlists${query}`, {
+        credentials: 'include' // 包含 cookies
+      })
+      if (!response.ok) {
+        throw new Error('Failed to fetch Spotify playlists')
+      }
+      const page: { items: SpotifyPlaylist[], next_cursor?: string } = await response.json()
+      playlists.push(...page.items)
+      cursor = page.next_cursor ?? ''
+    } while (cursor)
+    return playlists
   },
 
-  // 迁移歌曲到 Spotify 歌单 (不再需要手动传递认证信息)
-  transferTracks: async (playlistId: string, tracks: Track[]): Promise<void> => {
-    const trackNames = tracks.map(track => track.title)
-    
-    const response = await fetch(`${API_BASE}/spotify/playlists/${playlistId}/tracks`, {
+  // 创建迁移任务，立即返回任务 ID，迁移在后台进行
+  createTransferJob: async (playlistId: string, tracks: Track[]): Promise<string> => {
+    const response = await fetch(`${API_BASE}/jobs`, {
       method: 'POST',
       headers: {
         'Content-Type': 'application/json'
       },
       credentials: 'include', // 包含 cookies
       body: JSON.stringify({
-        track_names: trackNames
+        playlist_id: playlistId,
+        tracks
       })
     })
-    
+
     if (!response.ok) {
-      throw new Error('Failed to transfer tracks')
+      throw new Error('Failed to create transfer job')
     }
+    const created: { job_id: string } = await response.json()
+    return created.job_id
+  },
+
+  // 查询迁移任务的状态和进度
+  getTransferJob: async (jobId: string): Promise<TransferJob> => {
+    const response = await fetch(`${API_BASE}/jobs/${jobId}`, {
+      credentials: 'include'
+    })
+    if (!response.ok) {
+      throw new Error('Failed to get transfer job')
+    }
+    return response.json()
   },
 
   // 登出
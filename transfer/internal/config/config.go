@@ -0,0 +1,336 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config 服务的全部配置
+// 加载顺序：默认值 < 配置文件 < 环境变量 < 命令行参数
+type Config struct {
+	Server     ServerConfig     `yaml:"server"`
+	Spotify    SpotifyConfig    `yaml:"spotify"`
+	Frontend   FrontendConfig   `yaml:"frontend"`
+	Session    SessionConfig    `yaml:"session"`
+	TokenStore TokenStoreConfig `yaml:"token_store"`
+	Jobs       JobsConfig       `yaml:"jobs"`
+	Matcher    MatcherConfig    `yaml:"matcher"`
+	RateLimit  RateLimitConfig  `yaml:"rate_limit"`
+}
+
+type ServerConfig struct {
+	ListenAddr        string        `yaml:"listen_addr"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	// WriteTimeout 为 0 表示不限制，SSE 进度推送需要长连接
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+}
+
+type SpotifyConfig struct {
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+	RedirectURL  string `yaml:"redirect_url"` // OAuth 回调地址，需要在 Spotify 后台登记
+}
+
+type FrontendConfig struct {
+	URL string `yaml:"url"` // OAuth 完成后跳转回的前端地址
+	// AllowedOrigins 允许跨域访问的来源，为空时只允许前端地址的来源
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+type SessionConfig struct {
+	Secret      string        `yaml:"secret"` // base64 编码的签名密钥
+	Dir         string        `yaml:"dir"`    // 会话保存目录，为空时会话只在内存中
+	Secure      bool          `yaml:"secure"`
+	MaxAge      time.Duration `yaml:"max_age"`
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+}
+
+type TokenStoreConfig struct {
+	Backend       string `yaml:"backend"` // memory、file 或 sqlite
+	Path          string `yaml:"path"`
+	EncryptionKey string `yaml:"encryption_key"` // base64 编码的 32 字节密钥
+}
+
+type JobsConfig struct {
+	Dir     string `yaml:"dir"`
+	Workers int    `yaml:"workers"`
+}
+
+type MatcherConfig struct {
+	CandidateLimit  int     `yaml:"candidate_limit"`
+	ReviewThreshold float64 `yaml:"review_threshold"`
+	SearchWorkers   int     `yaml:"search_workers"` // 每批歌曲并发搜索的数量
+	// 时长相差超过该值的候选不会被自动采用，0 表示不限制
+	DurationTolerance time.Duration `yaml:"duration_tolerance"`
+}
+
+// RateLimitConfig 所有用户共享的 Spotify 请求预算
+type RateLimitConfig struct {
+	RequestsPerSecond float64       `yaml:"requests_per_second"`
+	Burst             int           `yaml:"burst"`
+	MaxRetries        int           `yaml:"max_retries"`
+	MaxBackoff        time.Duration `yaml:"max_backoff"`
+}
+
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			ListenAddr:        ":8081",
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			IdleTimeout:       120 * time.Second,
+		},
+		Frontend: FrontendConfig{
+			URL: "http://localhost:3000",
+		},
+		Session: SessionConfig{
+			Dir:         "data/sessions",
+			MaxAge:      7 * 24 * time.Hour,
+			IdleTimeout: 24 * time.Hour,
+		},
+		TokenStore: TokenStoreConfig{
+			Backend: "memory",
+		},
+		Jobs: JobsConfig{
+			Dir:     "data/jobs",
+			Workers: 2,
+		},
+		Matcher: MatcherConfig{
+			CandidateLimit:    5,
+			ReviewThreshold:   0.6,
+			SearchWorkers:     4,
+			DurationTolerance: 10 * time.Second,
+		},
+		RateLimit: RateLimitConfig{
+			RequestsPerSecond: 5,
+			Burst:             10,
+			MaxRetries:        5,
+			MaxBackoff:        30 * time.Second,
+		},
+	}
+}
+
+// Load 解析命令行参数并加载配置，返回的配置已经通过校验
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("transfer", flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv("TRANSFER_CONFIG"), "path to YAML config file")
+	overrides := registerFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+	if *configPath != "" {
+		if err := cfg.loadFile(*configPath); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	// 只应用显式传入的参数，未传入的不覆盖前面的来源
+	fs.Visit(func(f *flag.Flag) {
+		if apply, ok := overrides[f.Name]; ok {
+			apply(cfg)
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadEnv 读取 TRANSFER_ 前缀的环境变量
+func (c *Config) loadEnv() error {
+	strs := map[string]*string{
+		"TRANSFER_LISTEN_ADDR":           &c.Server.ListenAddr,
+		"TRANSFER_SPOTIFY_CLIENT_ID":     &c.Spotify.ClientID,
+		"TRANSFER_SPOTIFY_CLIENT_SECRET": &c.Spotify.ClientSecret,
+		"TRANSFER_SPOTIFY_REDIRECT_URL":  &c.Spotify.RedirectURL,
+		"TRANSFER_FRONTEND_URL":          &c.Frontend.URL,
+		"TRANSFER_SESSION_SECRET":        &c.Session.Secret,
+		"TRANSFER_SESSION_DIR":           &c.Session.Dir,
+		"TRANSFER_TOKEN_STORE":           &c.TokenStore.Backend,
+		"TRANSFER_TOKEN_STORE_PATH":      &c.TokenStore.Path,
+		"TRANSFER_TOKEN_ENCRYPTION_KEY":  &c.TokenStore.EncryptionKey,
+		"TRANSFER_JOBS_DIR":              &c.Jobs.Dir,
+	}
+	for name, target := range strs {
+		if v, ok := os.LookupEnv(name); ok {
+			*target = v
+		}
+	}
+
+	if v, ok := os.LookupEnv("TRANSFER_CORS_ORIGINS"); ok {
+		c.Frontend.AllowedOrigins = splitList(v)
+	}
+
+	var errs []error
+	if v, ok := os.LookupEnv("TRANSFER_SESSION_SECURE"); ok {
+		secure, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("TRANSFER_SESSION_SECURE: %w", err))
+		}
+		c.Session.Secure = secure
+	}
+	if v, ok := os.LookupEnv("TRANSFER_JOB_WORKERS"); ok {
+		workers, err := strconv.Atoi(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("TRANSFER_JOB_WORKERS: %w", err))
+		}
+		c.Jobs.Workers = workers
+	}
+	if v, ok := os.LookupEnv("TRANSFER_SPOTIFY_RPS"); ok {
+		rps, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("TRANSFER_SPOTIFY_RPS: %w", err))
+		}
+		c.RateLimit.RequestsPerSecond = rps
+	}
+
+	durations := map[string]*time.Duration{
+		"TRANSFER_READ_TIMEOUT":  &c.Server.ReadTimeout,
+		"TRANSFER_WRITE_TIMEOUT": &c.Server.WriteTimeout,
+		"TRANSFER_IDLE_TIMEOUT":  &c.Server.IdleTimeout,
+	}
+	for name, target := range durations {
+		if v, ok := os.LookupEnv(name); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+			*target = d
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// registerFlags 注册命令行参数，返回参数名到覆盖函数的映射
+func registerFlags(fs *flag.FlagSet) map[string]func(*Config) {
+	listen := fs.String("listen", "", "listen address, e.g. :8081")
+	clientID := fs.String("spotify-client-id", "", "Spotify client ID")
+	clientSecret := fs.String("spotify-client-secret", "", "Spotify client secret")
+	redirectURL := fs.String("spotify-redirect-url", "", "Spotify OAuth redirect URL")
+	frontendURL := fs.String("frontend-url", "", "frontend URL to redirect to after login")
+	origins := fs.String("cors-origins", "", "comma separated list of allowed CORS origins")
+	rps := fs.Float64("spotify-rps", 0, "Spotify requests per second shared by all users")
+
+	return map[string]func(*Config){
+		"listen":                func(c *Config) { c.Server.ListenAddr = *listen },
+		"spotify-client-id":     func(c *Config) { c.Spotify.ClientID = *clientID },
+		"spotify-client-secret": func(c *Config) { c.Spotify.ClientSecret = *clientSecret },
+		"spotify-redirect-url":  func(c *Config) { c.Spotify.RedirectURL = *redirectURL },
+		"frontend-url":          func(c *Config) { c.Frontend.URL = *frontendURL },
+		"cors-origins":          func(c *Config) { c.Frontend.AllowedOrigins = splitList(*origins) },
+		"spotify-rps":           func(c *Config) { c.RateLimit.RequestsPerSecond = *rps },
+	}
+}
+
+// Validate 检查配置，一次性返回所有问题
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.ListenAddr != "", "server.listen_addr is required")
+	check(c.Spotify.ClientID != "", "spotify.client_id is required")
+	check(c.Spotify.ClientSecret != "", "spotify.client_secret is required")
+	check(isAbsoluteURL(c.Spotify.RedirectURL), "spotify.redirect_url must be an absolute URL, got %q", c.Spotify.RedirectURL)
+	check(isAbsoluteURL(c.Frontend.URL), "frontend.url must be an absolute URL, got %q", c.Frontend.URL)
+
+	if len(c.Frontend.AllowedOrigins) == 0 && isAbsoluteURL(c.Frontend.URL) {
+		c.Frontend.AllowedOrigins = []string{originOf(c.Frontend.URL)}
+	}
+	for _, origin := range c.Frontend.AllowedOrigins {
+		check(isAbsoluteURL(origin) && originOf(origin) == origin, "frontend.allowed_origins: %q is not an origin like https://example.com", origin)
+	}
+
+	if c.Session.Secret != "" {
+		_, err := base64.StdEncoding.DecodeString(c.Session.Secret)
+		check(err == nil, "session.secret must be base64")
+	}
+	check(c.Session.MaxAge > 0, "session.max_age must be positive")
+	check(c.Session.IdleTimeout > 0, "session.idle_timeout must be positive")
+
+	switch c.TokenStore.Backend {
+	case "memory":
+	case "file", "sqlite":
+		check(c.TokenStore.Path != "", "token_store.path is required for backend %s", c.TokenStore.Backend)
+		check(c.TokenStore.EncryptionKey != "", "token_store.encryption_key is required for backend %s", c.TokenStore.Backend)
+	default:
+		check(false, "token_store.backend must be memory, file or sqlite, got %q", c.TokenStore.Backend)
+	}
+
+	check(c.Jobs.Dir != "", "jobs.dir is required")
+	check(c.Jobs.Workers > 0, "jobs.workers must be positive")
+	check(c.Matcher.CandidateLimit > 0 && c.Matcher.CandidateLimit <= 50, "matcher.candidate_limit must be between 1 and 50")
+	check(c.Matcher.ReviewThreshold >= 0 && c.Matcher.ReviewThreshold <= 1, "matcher.review_threshold must be between 0 and 1")
+	check(c.Matcher.SearchWorkers > 0, "matcher.search_workers must be positive")
+	check(c.Matcher.DurationTolerance >= 0, "matcher.duration_tolerance must not be negative")
+	check(c.RateLimit.RequestsPerSecond > 0, "rate_limit.requests_per_second must be positive")
+	check(c.RateLimit.Burst > 0, "rate_limit.burst must be positive")
+	check(c.RateLimit.MaxRetries >= 0, "rate_limit.max_retries must not be negative")
+	check(c.RateLimit.MaxBackoff > 0, "rate_limit.max_backoff must be positive")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// SessionSecret 解码后的会话签名密钥
+func (c *Config) SessionSecret() []byte {
+	key, _ := base64.StdEncoding.DecodeString(c.Session.Secret)
+	return key
+}
+
+func isAbsoluteURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func originOf(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
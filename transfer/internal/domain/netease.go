@@ -6,27 +6,191 @@ type Track struct {
 	Title  string `json:"title"`
 	Artist string `json:"artist"`
 	Album  string `json:"album,omitempty"`
+	// 标题的别名和译名，常常是 Spotify 上使用的英文名或官方名
+	Aliases []string `json:"aliases,omitempty"`
+	// 逐位艺术家及其别名，Artist 是这些名字拼接后的结果
+	Artists      []Artist `json:"artists,omitempty"`
+	AlbumAliases []string `json:"album_aliases,omitempty"`
+	// 源平台的歌曲 ID，例如网易云的 song id
+	SourceID   string `json:"source_id,omitempty"`
+	DurationMs int    `json:"duration_ms,omitempty"`
+	// 从标题的 "feat. X" 等注释中提取的合作艺术家
+	FeaturedArtists []string `json:"featured_artists,omitempty"`
 	// 用于匹配的唯一标识，组合 title + artist
 	MatchKey string `json:"match_key"`
 }
 
+// Artist 一位艺术家，Aliases 包含别名和译名
+type Artist struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
 // MusicList 歌单的完整表示
 type MusicList struct {
 	Name   string  `json:"name"`
 	ID     string  `json:"id"`
 	Tracks []Track `json:"tracks"`
+	// UnavailableIDs 歌单中列出但网易云不再返回详情的歌曲（下架或失去版权），不在 Tracks 中
+	UnavailableIDs []string `json:"unavailable_ids,omitempty"`
 }
 
+type TransferStatus string
+
+const (
+	TransferRunning   TransferStatus = "running"
+	TransferCompleted TransferStatus = "completed"
+	TransferCancelled TransferStatus = "cancelled" // 被取消，未处理的歌曲保持待处理，可以续传
+	TransferFailed    TransferStatus = "failed"
+	// 预览完成：只做了匹配，没有写入歌单
+	TransferPreviewed TransferStatus = "previewed"
+)
+
 // TransferResult 传输结果，明确记录成功/失败
 type TransferResult struct {
-	TotalTracks   int           `json:"total_tracks"`
-	SuccessCount  int           `json:"success_count"`
-	FailedTracks  []FailedTrack `json:"failed_tracks"`
-	SuccessTracks []string      `json:"success_tracks"` // Spotify track IDs
+	Status        TransferStatus `json:"status,omitempty"`
+	TotalTracks   int            `json:"total_tracks"`
+	Records       []TrackRecord  `json:"records"` // 每首源歌曲一条，按源歌单顺序
+	SuccessCount  int            `json:"success_count"`
+	FailedTracks  []FailedTrack  `json:"failed_tracks"`
+	SuccessTracks []string       `json:"success_tracks"` // Spotify track IDs
+	Matches       []MatchedTrack `json:"matches"`        // 每首匹配歌曲的置信度
+	NeedsReview   []ReviewTrack  `json:"needs_review"`   // 置信度不足，未添加
+	// AlreadyPresent 匹配结果已在目标歌单中，跳过未重复添加
+	AlreadyPresent []MatchedTrack `json:"already_present"`
+	// RateLimitWaitMs 因 Spotify 限流累计等待的时间
+	RateLimitWaitMs int64 `json:"rate_limit_wait_ms"`
+}
+
+type TrackStatus string
+
+const (
+	TrackPending        TrackStatus = "pending"
+	TrackMatched        TrackStatus = "matched" // 已匹配，尚未加入歌单
+	TrackAdded          TrackStatus = "added"
+	TrackNeedsReview    TrackStatus = "needs_review"
+	TrackAlreadyPresent TrackStatus = "already_present"
+	TrackSkipped        TrackStatus = "skipped" // 待确认时被用户跳过
+	TrackFailed         TrackStatus = "failed"
+)
+
+// TrackRecord 单首歌曲在迁移中的完整记录
+// 添加失败时 SpotifyID 仍保留，可以据此区分搜索失败和添加失败
+type TrackRecord struct {
+	Index      int         `json:"index"` // 歌曲在源歌单中的位置
+	Track      Track       `json:"track"`
+	Status     TrackStatus `json:"status"`
+	SpotifyID  string      `json:"spotify_id,omitempty"`
+	Confidence float64     `json:"confidence,omitempty"`
+	Match      *Candidate  `json:"match,omitempty"` // 选中的 Spotify 歌曲，预览时即为建议的匹配
+	// Strategy 找到匹配所用的搜索方式
+	Strategy MatchStrategy `json:"strategy,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// MatchStrategy 找到候选歌曲所用的搜索方式，按尝试的先后排列
+type MatchStrategy string
+
+const (
+	MatchByText      MatchStrategy = "text"      // 按标题和艺术家搜索
+	MatchByAlias     MatchStrategy = "alias"     // 按别名或译名搜索
+	MatchByRomanized MatchStrategy = "romanized" // 按罗马字搜索
+)
+
+// MatchedTrack 已匹配的歌曲及匹配置信度
+type MatchedTrack struct {
+	Track      Track         `json:"track"`
+	SpotifyID  string        `json:"spotify_id"`
+	Confidence float64       `json:"confidence"`
+	Strategy   MatchStrategy `json:"strategy,omitempty"`
+}
+
+// ReviewTrack 匹配不确定、需要人工确认的歌曲
+type ReviewTrack struct {
+	Index      int         `json:"index"` // 歌曲在源歌单中的位置
+	Track      Track       `json:"track"`
+	Confidence float64     `json:"confidence"`
+	Candidates []Candidate `json:"candidates"`
+}
+
+// Candidate Spotify 上的一首候选歌曲
+type Candidate struct {
+	SpotifyID  string         `json:"spotify_id"`
+	Name       string         `json:"name"`
+	Artists    []string       `json:"artists"`
+	Album      string         `json:"album"`
+	DurationMs int            `json:"duration_ms"`
+	Confidence float64        `json:"confidence"`
+	Score      ScoreBreakdown `json:"score"`
+	Strategy   MatchStrategy  `json:"strategy,omitempty"` // 搜到这首候选所用的方式
+	Version    VersionType    `json:"version,omitempty"`
+}
+
+// ScoreBreakdown 各项相似度，取值 0~1
+// 源歌曲缺少对应信息时该项为 nil，不参与加权
+type ScoreBreakdown struct {
+	Title    float64  `json:"title"`
+	Artist   *float64 `json:"artist,omitempty"`
+	Album    *float64 `json:"album,omitempty"`
+	Duration *float64 `json:"duration,omitempty"`
+	// Version 版本与期望不符时乘到置信度上的折扣，版本相符时为 nil
+	Version *float64 `json:"version,omitempty"`
+}
+
+// VersionType 歌曲的版本类型，从标题中的注释判断
+type VersionType string
+
+const (
+	VersionOriginal     VersionType = "original" // 没有版本注释，视为录音室原版
+	VersionLive         VersionType = "live"
+	VersionRemix        VersionType = "remix"
+	VersionRemaster     VersionType = "remaster"
+	VersionAcoustic     VersionType = "acoustic"
+	VersionInstrumental VersionType = "instrumental"
+	VersionCover        VersionType = "cover"
+	VersionKaraoke      VersionType = "karaoke"
+	VersionSpedUp       VersionType = "sped-up" // 加速、减速等变速版本
+)
+
+// VersionPreference 一次迁移对候选版本的偏好，零值表示优先与源歌曲相同的版本
+type VersionPreference struct {
+	// PreferOriginal 总是优先录音室原版，源歌曲是现场版等其他版本时也一样
+	PreferOriginal bool `json:"prefer_original,omitempty"`
+	// AllowRemaster 重制版与原版等同，互相替代时不降低排名
+	AllowRemaster bool `json:"allow_remaster,omitempty"`
 }
 
 // FailedTrack 失败的歌曲，不静默忽略
 type FailedTrack struct {
+	Index int    `json:"index"` // 歌曲在源歌单中的位置
 	Track Track  `json:"track"`
 	Error string `json:"error"`
 }
+
+type TransferEventType string
+
+const (
+	EventSearching TransferEventType = "searching"
+	EventMatched   TransferEventType = "matched"
+	EventFailed    TransferEventType = "failed"
+	EventAdded     TransferEventType = "added"
+	// 置信度低于阈值，等待人工确认
+	EventNeedsReview TransferEventType = "needs_review"
+	// 匹配结果已在目标歌单中，跳过
+	EventAlreadyPresent TransferEventType = "already_present"
+)
+
+// TransferEvent 单首歌曲的进度事件，附带当前的累计数量
+type TransferEvent struct {
+	Type         TransferEventType `json:"type"`
+	Index        int               `json:"index"` // 歌曲在源歌单中的位置
+	Track        Track             `json:"track"`
+	SpotifyID    string            `json:"spotify_id,omitempty"`
+	Confidence   float64           `json:"confidence,omitempty"`
+	Error        string            `json:"error,omitempty"`
+	TotalTracks  int               `json:"total_tracks"`
+	SuccessCount int               `json:"success_count"`
+	FailedCount  int               `json:"failed_count"`
+	// RateLimitWaitMs 截至该事件因限流累计等待的时间
+	RateLimitWaitMs int64 `json:"rate_limit_wait_ms"`
+}
@@ -0,0 +1,90 @@
+package normalize
+
+import "strings"
+
+// traditionalPairs 常用繁体字到简体字的对照，每两个字一组：繁体在前，简体在后
+// 只收录一对一的字，"乾"、"著"、"藉" 这类繁简一对多的字不转换
+const traditionalPairs = `
+萬万 與与 專专 業业 叢丛 東东 絲丝 兩两 嚴严 喪丧 個个 豐丰 臨临 為为 麗丽 舉举 麼么 義义 烏乌 樂乐
+喬乔 習习 鄉乡 書书 買买 亂乱 爭争 於于 虧亏 雲云 亞亚 產产 畝亩 親亲 億亿 僅仅 從从 侖仑 倉仓 儀仪
+們们 價价 眾众 優优 會会 傘伞 偉伟 傳传 傷伤 倫伦 偽伪 體体 餘余 傭佣 俠侠 侶侣 偵侦 側侧 僑侨 儂侬
+倆俩 儷俪 儉俭 債债 傾倾 償偿 儲储 兒儿 兌兑 黨党 蘭兰 關关 興兴 養养 獸兽 內内 岡冈 冊册 寫写 軍军
+農农 馮冯 衝冲 決决 況况 凍冻 淨净 涼凉 減减 湊凑 凜凛 幾几 鳳凤 憑凭 凱凯 擊击 鑿凿 劃划 劉刘 則则
+剛刚 創创 刪删 別别 劑剂 劍剑 剝剥 劇剧 勸劝 辦办 務务 動动 勵励 勁劲 勞劳 勢势 勳勋 勻匀 匯汇 區区
+醫医 華华 協协 單单 賣卖 盧卢 鹵卤 臥卧 衛卫 卻却 廠厂 廳厅 曆历 歷历 厲厉 壓压 厭厌 廁厕 廂厢 廈厦
+廚厨 縣县 參参 雙双 發发 變变 敘叙 疊叠 葉叶 號号 嘆叹 歎叹 嘰叽 吳吴 嗎吗 啞哑 員员 聽听 啟启 嘔呕
+唄呗 嗆呛 嗚呜 詠咏 嚨咙 響响 嘩哗 喚唤 嘍喽 噓嘘 嚕噜 囑嘱 圍围 園园 圓圆 團团 國国 圖图 聖圣 場场
+壞坏 塊块 堅坚 壇坛 壩坝 墳坟 墜坠 壘垒 執执 報报 塵尘 牆墙 壯壮 聲声 殼壳 壺壶 處处 備备 復复 複复
+夠够 頭头 夾夹 奪夺 奮奋 獎奖 婦妇 媽妈 嫵妩 婁娄 嬌娇 娛娱 嫻娴 嬰婴 嬸婶 孫孙 學学 寧宁 寶宝 實实
+寵宠 審审 憲宪 宮宫 寬宽 賓宾 寢寝 對对 尋寻 導导 壽寿 將将 爾尔 嘗尝 堯尧 尷尴 屍尸 盡尽 儘尽 層层
+屬属 屢屡 嶼屿 歲岁 豈岂 崗岗 島岛 嶺岭 嶽岳 巒峦 巔巅 鞏巩 幣币 帥帅 師师 帳帐 帶带 幫帮 幹干 並并
+廣广 慶庆 廬庐 庫库 應应 廟庙 龐庞 廢废 開开 異异 棄弃 張张 彌弥 彎弯 彈弹 強强 歸归 當当 噹当 錄录
+彥彦 徹彻 徑径 後后 憶忆 懺忏 憂忧 懷怀 態态 憐怜 總总 戀恋 懇恳 惡恶 惱恼 悅悦 懸悬 驚惊 懼惧 慘惨
+懲惩 憊惫 慚惭 慣惯 願愿 懶懒 戲戏 戰战 戶户 撲扑 擴扩 掃扫 揚扬 擾扰 撫抚 拋抛 搶抢 護护 擔担 擬拟
+攏拢 擁拥 攔拦 擰拧 撥拨 擇择 掛挂 摯挚 撓挠 擋挡 掙挣 擠挤 揮挥 撈捞 損损 撿捡 換换 據据 擲掷 攬揽
+擱搁 摟搂 攪搅 攜携 攝摄 擺摆 搖摇 攤摊 撐撑 敵敌 斂敛 數数 齋斋 鬥斗 斬斩 斷断 時时 曠旷 晝昼 顯显
+晉晋 曬晒 曉晓 暈晕 暉晖 暫暂 曖暧 術术 機机 殺杀 雜杂 權权 條条 來来 楊杨 極极 構构 櫃柜 檸柠 棟栋
+欄栏 樹树 樣样 檔档 橋桥 夢梦 檢检 櫻樱 橫横 槍枪 楓枫 棲栖 標标 樓楼 歡欢 歐欧 殘残 毀毁 畢毕 氣气
+漢汉 湯汤 溝沟 沒没 淪沦 滄沧 潑泼 淚泪 澤泽 潔洁 灑洒 濁浊 測测 濟济 渾浑 濃浓 濤涛 漣涟 漲涨 澀涩
+漸渐 淵渊 漁渔 溫温 灣湾 濕湿 潰溃 濺溅 滾滚 滿满 濾滤 灘滩 瀟潇 瀾澜 滅灭 燈灯 靈灵 災灾 爐炉 燉炖
+煉炼 爍烁 燭烛 煩烦 燒烧 燙烫 熱热 煥焕 營营 愛爱 爺爷 牽牵 犧牺 猶犹 狀状 獨独 狹狭 獅狮 獄狱 貓猫
+獵猎 獻献 現现 環环 瑪玛 瓊琼 電电 畫画 暢畅 療疗 瘋疯 癢痒 癡痴 癒愈 盞盏 監监 蓋盖 盤盘 盜盗 睜睁
+瞞瞒 礦矿 碼码 磚砖 礎础 確确 禮礼 禍祸 禪禅 離离 禿秃 種种 稱称 積积 穩稳 穀谷 窮穷 竊窃 窯窑 豎竖
+競竞 筆笔 節节 範范 築筑 簡简 簾帘 簽签 籤签 籃篮 籠笼 類类 糧粮 糾纠 紅红 紀纪 約约 級级 紋纹 納纳
+紐纽 純纯 紙纸 紛纷 線线 綫线 練练 組组 細细 織织 終终 紹绍 經经 結结 給给 絕绝 統统 絢绚 絡络 繪绘
+繼继 績绩 續续 緒绪 維维 綿绵 綠绿 網网 緊紧 綻绽 緣缘 編编 緩缓 緻致 縫缝 縱纵 繞绕 繫系 罰罚 係系 羅罗
+聞闻 聯联 聰聪 聳耸 職职 聶聂 肅肃 腸肠 膚肤 腫肿 腦脑 脈脉 膽胆 勝胜 臉脸 膩腻 騰腾 臘腊 臟脏 髒脏
+艦舰 藝艺 蘆芦 蘇苏 甦苏 蘋苹 莖茎 薦荐 莊庄 萊莱 蓮莲 獲获 蕭萧 薩萨 藍蓝 藥药 蘊蕴 蘿萝 蕩荡 盪荡
+蟲虫 雖虽 蝦虾 螞蚂 蠶蚕 蠟蜡 蠻蛮 螢萤 補补 襯衬 襲袭 裝装 裡里 裏里 製制 褲裤 見见 規规 覓觅 視视
+覽览 覺觉 觀观 觸触 計计 訂订 認认 討讨 讓让 訓训 議议 記记 講讲 許许 論论 設设 訪访 證证 評评 識识
+詩诗 試试 話话 誠诚 詳详 語语 誤误 說说 誰谁 課课 調调 談谈 請请 諾诺 謀谋 謊谎 謝谢 謠谣 謎谜 譜谱
+譯译 讀读 讚赞 贊赞 豔艳 艷艳 貝贝 負负 貢贡 財财 責责 賢贤 敗败 貨货 質质 販贩 貪贪 貧贫 購购 貫贯
+貴贵 費费 貼贴 貿贸 賀贺 資资 賊贼 賞赏 賜赐 賴赖 賺赚 賽赛 贈赠 贏赢 趕赶 趙赵 趨趋 蹤踪 躍跃 輕轻
+車车 軌轨 軟软 轉转 輪轮 輝辉 載载 輸输 轟轰 辭辞 輩辈 邊边 遼辽 達达 遷迁 過过 邁迈 運运 還还 這这
+進进 遠远 違违 連连 遲迟 適适 選选 遺遗 遙遥 遊游 鄰邻 鄭郑 鄧邓 醜丑 釀酿 釋释 鑒鉴 針针 釣钓 鈴铃
+鉛铅 銀银 銅铜 鋒锋 鋼钢 錢钱 錦锦 錯错 鍵键 鍾钟 鐘钟 鍋锅 鎖锁 鏡镜 鏽锈 鐵铁 鑰钥 長长 門门
+閃闪 閉闭 問问 閒闲 間间 悶闷 閱阅 闊阔 闖闯 閻阎 鬧闹 陽阳 陰阴 陣阵 陳陈 陸陆 隊队 階阶 際际 隨随
+險险 隱隐 難难 雞鸡 雛雏 霧雾 靂雳 靜静 韓韩 韻韵 頁页 頂顶 項项 順顺 須须 預预 頑顽 頓顿 頌颂 領领
+頻频 題题 顏颜 額额 顧顾 風风 颱台 臺台 檯台 飄飘 飛飞 飯饭 飲饮 飽饱 餅饼 餓饿 館馆 饞馋 馬马 駕驾
+駛驶 驅驱 騎骑 驗验 驕骄 驟骤 驢驴 髮发 鬆松 鬍胡 鬱郁 魚鱼 鮮鲜 鯨鲸 鳥鸟 鳴鸣 鴨鸭 鴿鸽 鵝鹅 鶯莺
+鷹鹰 鹽盐 麥麦 麵面 黃黄 點点 黴霉 齊齐 齒齿 龍龙 龜龟 無无 舊旧 淺浅 煙烟 傑杰 薰熏 慮虑 嬪嫔 嬤嬷
+倖幸 夥伙 衆众 僕仆 託托 禦御 嚮向 癥症 蒐搜 捨舍 衚胡 迴回 纔才 兇凶 滷卤 閘闸 孃娘 奬奖 週周 佈布
+彙汇 隻只 祕秘 裊袅 衊蔑 準准 齣出 鍛锻 鑽钻 鑄铸 閣阁 闆板 闌阑 闡阐 隴陇 雋隽 霽霁 靨靥 韋韦 韌韧
+頒颁 頰颊 頸颈 頹颓 顆颗 顫颤 颳刮 颼飕 飢饥 飾饰 餃饺 餌饵 餵喂 饅馒 馴驯 駁驳 駐驻 駝驼 駭骇 騙骗
+騷骚 驛驿 驪骊 骯肮 鬢鬓 魯鲁 魷鱿 鯉鲤 鱗鳞 鳩鸠 鴉鸦 鴻鸿 鵑鹃 鵬鹏 鶴鹤 鷗鸥 鸚鹦 黷黩 鼴鼹 齡龄
+龔龚 姦奸 嬋婵 孿孪 屜屉 峽峡 巰巯 幟帜 弒弑 彿佛 徵征 恆恒 悵怅 惻恻 愴怆 慄栗 慪怄 憤愤 懣懑 戇戆
+挾挟 撻挞 擄掳 擻擞 攄摅 攢攒 攣挛 斃毙 暱昵 曇昙 朧胧 棗枣 樞枢 樺桦 樸朴 橢椭 檜桧 櫚榈 櫥橱 欒栾
+歟欤 歿殁 殤殇 氈毡 氫氢 汙污 洶汹 溈沩 滬沪 漿浆 潛潜 澆浇 澗涧 濱滨 瀅滢 瀉泻 瀋沈 瀏浏 灤滦 烴烃
+煒炜 煢茕 熒荧 燁烨 燐磷 燦灿 爛烂 牘牍 犢犊 狽狈 猙狰 獰狞 玀猡 琺珐 璉琏 瓏珑 甌瓯 畬畲 疇畴 瘂痖
+瘞瘗 癘疠 癟瘪 癮瘾 皚皑 皺皱 盃杯 眥眦 睏困 矯矫 碭砀 磯矶 礪砺 祿禄 禎祯 稅税 稈秆 穌稣 窩窝 窺窥
+竄窜 筍笋 箏筝 篩筛 簍篓 籌筹 糝糁 糞粪 緋绯 緗缃 緞缎 緯纬 縛缚 縮缩 繃绷 繡绣 繭茧 纏缠 纓缨 罌罂
+罷罢 羆罴 羋芈 翽翙 耬耧 聵聩 脅胁 脫脱 腎肾 膠胶 膿脓 臍脐 臏膑 舖铺 艱艰 芻刍 莧苋 萵莴 葦苇 蒼苍
+蓽荜 蔥葱 蔣蒋 蔦茑 蕁荨 蕎荞 蕪芜 薈荟 薊蓟 薺荠 藎荩 藹蔼 蘄蕲 虜虏 虛虚 蛻蜕 蜆蚬 蝕蚀 蝸蜗 螻蝼
+蟄蛰 蟬蝉 蠅蝇 衹只 袞衮 襖袄 覘觇 詐诈 詛诅 詞词 該该 詭诡 誇夸 誌志 誕诞 誘诱 誦诵 諒谅 諜谍 諧谐
+諫谏 諷讽 謁谒 謄誊 謙谦 謹谨 譏讥 譴谴 讎雠 豬猪 貞贞 貶贬 貸贷 賄贿 賠赔 賤贱 賦赋 賬账 賭赌 贅赘
+贓赃 贖赎 趲趱 跡迹 踐践 蹌跄 蹕跸 躉趸 軀躯 軒轩 軛轭 軸轴 輔辅 輯辑 轄辖 轍辙 辮辫 遜逊 遞递 邏逻
+郵邮 鄒邹 醬酱 釘钉 鈍钝 鈔钞 鉤钩 銘铭 銳锐 鋪铺 錘锤 鍍镀 鎮镇 鏈链 鐲镯 鑲镶 閨闺 閩闽 闈闱 闕阙
+陘陉 隕陨 霑沾 靄霭 韃鞑 韜韬 頃顷 頗颇 頤颐 頷颔 顎颚 顛颠 颶飓 飪饪 餑饽 饑饥 馳驰 駒驹 騁骋 騫骞
+驍骁 髏髅 鬨哄 鬩阋 魎魉 鮑鲍 鯊鲨 鰐鳄 鱷鳄 鳶鸢 鴕鸵 鵲鹊 鶩鹜 鸞鸾 麩麸 黲黪 鼉鼍 齜龇 龕龛
+`
+
+var simplifiedTable = buildSimplifiedTable(traditionalPairs)
+
+func buildSimplifiedTable(pairs string) map[rune]rune {
+	table := make(map[rune]rune)
+	for _, pair := range strings.Fields(pairs) {
+		runes := []rune(pair)
+		table[runes[0]] = runes[1]
+	}
+	return table
+}
+
+// Simplified 繁体转简体，表中没有的字保持不变
+func Simplified(s string) string {
+	return strings.Map(func(r rune) rune {
+		if simplified, ok := simplifiedTable[r]; ok {
+			return simplified
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,112 @@
+package normalize
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/ikawaha/kagome/v2/tokenizer"
+)
+
+// japaneseNames 人名读音表，格式见文件开头的说明
+//
+//go:embed japanese_names.txt
+var japaneseNames string
+
+var (
+	nameReadings = buildNameReadings(japaneseNames)
+	longestName  = longestKey(nameReadings)
+	// 词典解压后占用较多内存，第一次用到时才加载，服务启动时由 LoadJapanese 提前加载
+	japaneseWords = sync.OnceValues(func() (t *tokenizer.Tokenizer, err error) {
+		// 词典数据损坏时 kagome 会 panic，转换成错误，不影响正在进行的迁移
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("failed to load japanese dictionary: %v", r)
+			}
+		}()
+		return loadJapaneseDictionary()
+	})
+)
+
+// LoadJapanese 加载日语词典，加载失败时汉字只按拼音转换
+// 加载需要一秒左右，服务启动时调用，避免第一次迁移时等待
+func LoadJapanese() error {
+	_, err := japaneseWords()
+	return err
+}
+
+func buildNameReadings(table string) map[string]string {
+	readings := make(map[string]string)
+	for _, line := range strings.Split(table, "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if fields := strings.Fields(line); len(fields) > 1 {
+			readings[fields[0]] = strings.Join(fields[1:], " ")
+		}
+	}
+	return readings
+}
+
+func longestKey(table map[string]string) int {
+	longest := 0
+	for key := range table {
+		longest = max(longest, len([]rune(key)))
+	}
+	return longest
+}
+
+// japaneseReading 把文本中的汉字换成假名读音，其余字符保持不变
+// 先查人名表，剩下的汉字用 IPA 词典分词后取读音；named 表示是否命中了人名表
+// 有汉字查不到读音或词典不可用时 ok 为 false，这些汉字原样保留
+func japaneseReading(s string) (reading string, named, ok bool) {
+	var b strings.Builder
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		size := min(longestName, len(runes)-i)
+		for ; size > 0; size-- {
+			if kana, found := nameReadings[string(runes[i:i+size])]; found {
+				writeWord(&b, kana)
+				named = true
+				break
+			}
+		}
+		if size == 0 {
+			b.WriteRune(runes[i])
+			continue
+		}
+		i += size - 1
+	}
+
+	reading, ok = b.String(), true
+	if !strings.ContainsFunc(reading, isHan) {
+		return reading, named, ok
+	}
+
+	words, err := japaneseWords()
+	if err != nil {
+		return reading, named, false
+	}
+
+	b.Reset()
+	for _, token := range words.Tokenize(reading) {
+		if !strings.ContainsFunc(token.Surface, isHan) {
+			b.WriteString(token.Surface)
+			continue
+		}
+		kana, _ := token.Reading()
+		if kana == "" || kana == "*" {
+			ok = false
+			b.WriteString(token.Surface)
+			continue
+		}
+		writeWord(&b, kana)
+	}
+	return b.String(), named, ok
+}
+
+func isHan(r rune) bool {
+	return unicode.Is(unicode.Han, r)
+}
@@ -0,0 +1,13 @@
+//go:build !nojapanese
+
+package normalize
+
+import (
+	"github.com/ikawaha/kagome-dict/ipa"
+	"github.com/ikawaha/kagome/v2/tokenizer"
+)
+
+// loadJapaneseDictionary IPA 词典内嵌在程序中，约占 12MB
+func loadJapaneseDictionary() (*tokenizer.Tokenizer, error) {
+	return tokenizer.New(ipa.Dict(), tokenizer.OmitBosEos())
+}
@@ -0,0 +1,14 @@
+//go:build nojapanese
+
+package normalize
+
+import (
+	"errors"
+
+	"github.com/ikawaha/kagome/v2/tokenizer"
+)
+
+// loadJapaneseDictionary 使用 nojapanese 构建时不包含词典，日语汉字只能查人名表
+func loadJapaneseDictionary() (*tokenizer.Tokenizer, error) {
+	return nil, errors.New("built without japanese dictionary (nojapanese)")
+}
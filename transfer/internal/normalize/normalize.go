@@ -0,0 +1,154 @@
+// Package normalize 统一歌曲标题和艺术家的写法，供搜索和匹配共用
+package normalize
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/width"
+)
+
+// 成对的括号，里面的内容视为注释
+var brackets = map[rune]rune{
+	'(': ')',
+	'[': ']',
+	'{': '}',
+	'【': '】',
+	'〔': '〕',
+	'〖': '〗',
+}
+
+// 统一成 ASCII 的标点，全角字符已经由 width 折叠
+var punctuation = strings.NewReplacer(
+	"“", `"`, "”", `"`, "„", `"`, "「", `"`, "」", `"`, "『", `"`, "』", `"`,
+	"‘", "'", "’", "'", "`", "'", "´", "'",
+	"—", "-", "–", "-", "―", "-", "‐", "-", "‑", "-", "−", "-",
+	"〜", "~", "～", "~",
+	"・", " ", "·", " ", "•", " ", "‧", " ",
+	"…", "...", "、", ",", "。", ".",
+)
+
+var (
+	spaces = regexp.MustCompile(`\s+`)
+	// "feat. X"、"ft. X"、"featuring X"、"with X"，以及中文的 "合唱：X"
+	featuredPrefix = regexp.MustCompile(`(?i)^(?:feat\.?|ft\.?|featuring|with|duet with|合唱)\s*:?\s*`)
+	featuredInline = regexp.MustCompile(`(?i)\s+(?:feat\.?|ft\.?|featuring)\s+`)
+	// " - Live"、" - 2011 Remaster"、" - Originally Performed by X" 这类用短横线分隔的注释
+	// 英文关键词要求整词匹配，避免 "Delivery"、"Demonstration" 这样的标题被截断
+	dashAnnotation = regexp.MustCompile(`(?i)\s+-\s+(.*(?:\b(?:live|remix(?:es)?|mix|edit|version|remaster(?:ed)?|acoustic|unplugged|instrumental|karaoke|originally performed|in the style of|sped up|slowed|nightcore|cover|demo|mono|stereo|feat|ft)\b|伴奏|现场|版|翻唱|纯音乐|主题曲|插曲|片尾曲|片头曲).*)$`)
+	// "/" 两侧需要有空格，避免拆开 AC/DC 这样的名字
+	artistSeparator = regexp.MustCompile(`\s*(?:,|;|\s/\s|\s+x\s+|\s+×\s+)\s*`)
+)
+
+// Title 拆分后的标题
+type Title struct {
+	Name        string   // 去掉注释后的标题，只整理全半角和标点，不转换繁简
+	Annotations []string // 括号或 " - " 后面的注释，例如 "Live"、"电影《冰雪奇缘》主题曲"
+	Featured    []string // 从 "feat. X" 之类的注释中提取的艺术家
+}
+
+// Fold 全角转半角并统一标点和空白，保留原有的大小写和繁简
+func Fold(s string) string {
+	s = width.Fold.String(s)
+	s = punctuation.Replace(s)
+	return strings.TrimSpace(spaces.ReplaceAllString(s, " "))
+}
+
+// Key 用于比较的形式：在 Fold 的基础上繁体转简体、转小写、去掉标点
+func Key(s string) string {
+	s = Simplified(Fold(s))
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			return ' '
+		}
+		return unicode.ToLower(r)
+	}, s)
+	return strings.TrimSpace(spaces.ReplaceAllString(s, " "))
+}
+
+// ParseTitle 去掉标题中的括号注释，并从注释中提取合作艺术家
+// 去掉注释后标题为空时（整个标题都在括号里），保留原标题
+func ParseTitle(raw string) Title {
+	folded := Fold(raw)
+
+	var title Title
+	name := stripBrackets(folded, &title.Annotations)
+	if m := dashAnnotation.FindStringSubmatchIndex(name); m != nil {
+		title.Annotations = append(title.Annotations, strings.TrimSpace(name[m[2]:m[3]]))
+		name = name[:m[0]]
+	}
+	if parts := featuredInline.Split(name, 2); len(parts) == 2 {
+		name = parts[0]
+		title.Featured = append(title.Featured, SplitArtists(parts[1])...)
+	}
+
+	for _, annotation := range title.Annotations {
+		if loc := featuredPrefix.FindStringIndex(annotation); loc != nil {
+			title.Featured = append(title.Featured, SplitArtists(annotation[loc[1]:])...)
+		}
+	}
+
+	title.Name = strings.TrimSpace(spaces.ReplaceAllString(name, " "))
+	if title.Name == "" {
+		title.Name = folded
+	}
+	return title
+}
+
+// SplitArtists 拆分 "A, B"、"A / B"、"A x B"、"A feat. B" 形式的艺术家字符串
+// "&" 常见于组合名（例如 Simon & Garfunkel），不作为分隔符
+func SplitArtists(raw string) []string {
+	raw = Fold(raw)
+	raw = featuredInline.ReplaceAllString(raw, ",")
+
+	var artists []string
+	for _, a := range artistSeparator.Split(raw, -1) {
+		if a = strings.TrimSpace(a); a != "" {
+			artists = append(artists, a)
+		}
+	}
+	return artists
+}
+
+// stripBrackets 去掉所有成对括号中的内容，内容按出现顺序记入 annotations
+// 嵌套的括号整体算一条注释，不成对的括号保留原样
+func stripBrackets(s string, annotations *[]string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		closing, ok := brackets[runes[i]]
+		if !ok {
+			b.WriteRune(runes[i])
+			continue
+		}
+
+		end := matchBracket(runes, i, closing)
+		if end < 0 {
+			b.WriteRune(runes[i])
+			continue
+		}
+		if content := strings.TrimSpace(string(runes[i+1 : end])); content != "" {
+			*annotations = append(*annotations, content)
+		}
+		b.WriteRune(' ')
+		i = end
+	}
+	return b.String()
+}
+
+func matchBracket(runes []rune, start int, closing rune) int {
+	depth := 0
+	for j := start; j < len(runes); j++ {
+		switch runes[j] {
+		case runes[start]:
+			depth++
+		case closing:
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,78 @@
+package normalize
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseTitle(t *testing.T) {
+	tests := []struct {
+		in          string
+		name        string
+		annotations []string
+		featured    []string
+	}{
+		{"晴天", "晴天", nil, nil},
+		{"Live Forever", "Live Forever", nil, nil},
+		{"Live Forever - Remastered", "Live Forever", []string{"Remastered"}, nil},
+		{"Yellow - Live in Buenos Aires", "Yellow", []string{"Live in Buenos Aires"}, nil},
+		// 全角括号和全角字母折叠成半角
+		{"Ｌｅｍｏｎ（Ｌｉｖｅ）", "Lemon", []string{"Live"}, nil},
+		{"光年之外【电影《太空旅客》中文主题曲】", "光年之外", []string{"电影《太空旅客》中文主题曲"}, nil},
+		// 嵌套的括号整体算一条注释
+		{"Song (Remix (Radio Edit))", "Song", []string{"Remix (Radio Edit)"}, nil},
+		{"Song (Live", "Song (Live", nil, nil},
+		{"算什么男人 - 电影《天台爱情》插曲", "算什么男人", []string{"电影《天台爱情》插曲"}, nil},
+		{"追光者 - 电视剧《夏至未至》片尾曲", "追光者", []string{"电视剧《夏至未至》片尾曲"}, nil},
+		{"倒数 - 网剧主题曲", "倒数", []string{"网剧主题曲"}, nil},
+		// 英文关键词只在整词出现时才算注释
+		{"Song - Delivery", "Song - Delivery", nil, nil},
+		{"X - Demonstration", "X - Demonstration", nil, nil},
+		{"Song - Demo Version", "Song", []string{"Demo Version"}, nil},
+		{"Song - Remixes", "Song", []string{"Remixes"}, nil},
+		{"Stay (feat. Justin Bieber)", "Stay", []string{"feat. Justin Bieber"}, []string{"Justin Bieber"}},
+		{"Stay feat. Justin Bieber", "Stay", nil, []string{"Justin Bieber"}},
+		// 整个标题都在括号里时保留原标题
+		{"(Intro)", "(Intro)", []string{"Intro"}, nil},
+	}
+	for _, tt := range tests {
+		got := ParseTitle(tt.in)
+		if got.Name != tt.name || !slices.Equal(got.Annotations, tt.annotations) || !slices.Equal(got.Featured, tt.featured) {
+			t.Errorf("ParseTitle(%q) = %q %q %q, want %q %q %q", tt.in, got.Name, got.Annotations, got.Featured, tt.name, tt.annotations, tt.featured)
+		}
+	}
+}
+
+func TestSplitArtists(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"AC/DC", []string{"AC/DC"}},
+		{"Simon & Garfunkel", []string{"Simon & Garfunkel"}},
+		{"周杰伦 / 费玉清", []string{"周杰伦", "费玉清"}},
+		{"周杰伦，费玉清", []string{"周杰伦", "费玉清"}},
+		{"Calvin Harris x Dua Lipa", []string{"Calvin Harris", "Dua Lipa"}},
+		{"Post Malone feat. Swae Lee", []string{"Post Malone", "Swae Lee"}},
+	}
+	for _, tt := range tests {
+		if got := SplitArtists(tt.in); !slices.Equal(got, tt.want) {
+			t.Errorf("SplitArtists(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"ＡＢＣ", "abc"},
+		{"後來", "后来"},
+		{"Don’t Stop Me Now!", "don t stop me now"},
+	}
+	for _, tt := range tests {
+		if got := Key(tt.in); got != tt.want {
+			t.Errorf("Key(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package normalize
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/mozillazg/go-pinyin"
+)
+
+var pinyinArgs = pinyin.NewArgs()
+
+// 平假名到平文式罗马字，拗音放在前面优先匹配；片假名先转换为平假名
+var kanaPairs = []string{
+	"きゃ", "kya", "きゅ", "kyu", "きょ", "kyo", "しゃ", "sha", "しゅ", "shu", "しょ", "sho",
+	"ちゃ", "cha", "ちゅ", "chu", "ちょ", "cho", "にゃ", "nya", "にゅ", "nyu", "にょ", "nyo",
+	"ひゃ", "hya", "ひゅ", "hyu", "ひょ", "hyo", "みゃ", "mya", "みゅ", "myu", "みょ", "myo",
+	"りゃ", "rya", "りゅ", "ryu", "りょ", "ryo", "ぎゃ", "gya", "ぎゅ", "gyu", "ぎょ", "gyo",
+	"じゃ", "ja", "じゅ", "ju", "じょ", "jo", "びゃ", "bya", "びゅ", "byu", "びょ", "byo",
+	"ぴゃ", "pya", "ぴゅ", "pyu", "ぴょ", "pyo", "しぇ", "she", "ちぇ", "che", "じぇ", "je",
+	"てぃ", "ti", "でぃ", "di", "ふぁ", "fa", "ふぃ", "fi", "ふぇ", "fe", "ふぉ", "fo",
+	"うぃ", "wi", "うぇ", "we", "うぉ", "wo", "ゔぁ", "va", "ゔぃ", "vi", "ゔぇ", "ve", "ゔぉ", "vo",
+	"あ", "a", "い", "i", "う", "u", "え", "e", "お", "o",
+	"か", "ka", "き", "ki", "く", "ku", "け", "ke", "こ", "ko",
+	"さ", "sa", "し", "shi", "す", "su", "せ", "se", "そ", "so",
+	"た", "ta", "ち", "chi", "つ", "tsu", "て", "te", "と", "to",
+	"な", "na", "に", "ni", "ぬ", "nu", "ね", "ne", "の", "no",
+	"は", "ha", "ひ", "hi", "ふ", "fu", "へ", "he", "ほ", "ho",
+	"ま", "ma", "み", "mi", "む", "mu", "め", "me", "も", "mo",
+	"や", "ya", "ゆ", "yu", "よ", "yo",
+	"ら", "ra", "り", "ri", "る", "ru", "れ", "re", "ろ", "ro",
+	"わ", "wa", "ゐ", "i", "ゑ", "e", "を", "o", "ん", "n",
+	"が", "ga", "ぎ", "gi", "ぐ", "gu", "げ", "ge", "ご", "go",
+	"ざ", "za", "じ", "ji", "ず", "zu", "ぜ", "ze", "ぞ", "zo",
+	"だ", "da", "ぢ", "ji", "づ", "zu", "で", "de", "ど", "do",
+	"ば", "ba", "び", "bi", "ぶ", "bu", "べ", "be", "ぼ", "bo",
+	"ぱ", "pa", "ぴ", "pi", "ぷ", "pu", "ぺ", "pe", "ぽ", "po",
+	"ゔ", "vu", "ぁ", "a", "ぃ", "i", "ぅ", "u", "ぇ", "e", "ぉ", "o",
+	"ゃ", "ya", "ゅ", "yu", "ょ", "yo", "ゎ", "wa",
+}
+
+var kanaTable = buildKanaTable(kanaPairs)
+
+func buildKanaTable(pairs []string) map[string]string {
+	table := make(map[string]string, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		table[pairs[i]] = pairs[i+1]
+	}
+	return table
+}
+
+// 谚文音节的初声、中声、终声，按文化观光部 2000 年式，不处理音变
+var (
+	hangulInitials = []string{"g", "kk", "n", "d", "tt", "r", "m", "b", "pp", "s", "ss", "", "j", "jj", "ch", "k", "t", "p", "h"}
+	hangulMedials  = []string{"a", "ae", "ya", "yae", "eo", "e", "yeo", "ye", "o", "wa", "wae", "oe", "yo", "u", "wo", "we", "wi", "yu", "eu", "ui", "i"}
+	hangulFinals   = []string{"", "k", "k", "k", "n", "n", "n", "t", "l", "k", "m", "l", "l", "l", "p", "l", "m", "p", "p", "t", "t", "ng", "t", "t", "k", "t", "p", "t"}
+)
+
+const (
+	hangulBase  = 0xAC00
+	hangulLast  = 0xD7A3
+	hangulVowel = 21 * 28
+)
+
+// HasCJK 是否包含汉字、假名或谚文
+func HasCJK(s string) bool {
+	for _, r := range s {
+		if isCJK(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Romanize 把中日韩文字转换为拉丁字母，其余字符保持不变，结果为小写
+// 只返回最可能的一种写法，所有写法见 Romanizations
+func Romanize(s string) string {
+	return Romanizations(s)[0]
+}
+
+// Romanizations 文本所有可能的罗马字写法，最可能的排在前面，至少有一种
+// 汉语用汉语拼音，每个字之间用空格分隔；日语用平文式罗马字；谚文用文化观光部式
+// 含有假名的文本是日语，汉字按日语读音转换，不生成拼音
+// 只有汉字时无法区分中文和日语：命中日本人名表时日语读法在前，否则拼音在前
+func Romanizations(s string) []string {
+	folded := Fold(s)
+	if !strings.ContainsFunc(folded, isHan) {
+		return []string{romanizeText(folded, false)}
+	}
+
+	reading, named, ok := japaneseReading(folded)
+	japanese := romanizeText(reading, false)
+	if strings.ContainsFunc(folded, isKana) {
+		return []string{japanese}
+	}
+
+	chinese := romanizeText(Simplified(folded), true)
+	switch {
+	case named && japanese != chinese:
+		return []string{japanese, chinese}
+	case ok && japanese != chinese:
+		return []string{chinese, japanese}
+	default:
+		return []string{chinese}
+	}
+}
+
+// romanizeText 转换假名、谚文，pinyin 为 true 时汉字转换为拼音，否则汉字原样保留
+func romanizeText(s string, pinyinHan bool) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case pinyinHan && isHan(r):
+			if syllables := pinyin.SinglePinyin(r, pinyinArgs); len(syllables) > 0 {
+				writeWord(&b, syllables[0])
+				continue
+			}
+			b.WriteRune(r)
+		case isKana(r):
+			end := i
+			for end < len(runes) && isKana(runes[end]) {
+				end++
+			}
+			writeWord(&b, romanizeKana(runes[i:end]))
+			i = end - 1
+		case r >= hangulBase && r <= hangulLast:
+			end := i
+			for end < len(runes) && runes[end] >= hangulBase && runes[end] <= hangulLast {
+				end++
+			}
+			writeWord(&b, romanizeHangul(runes[i:end]))
+			i = end - 1
+		default:
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return strings.TrimSpace(spaces.ReplaceAllString(b.String(), " "))
+}
+
+// romanKey 罗马字比较形式：只保留字母和数字
+func romanKey(roman string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, roman)
+}
+
+// RomanEqual 两个名字是否有相同的罗马字写法，允许姓和名的顺序颠倒
+// 例如 "米津玄師" 与 "Kenshi Yonezu"、"周杰伦" 与 "Jielun Zhou"
+// 英文艺名不是罗马字，"周杰伦" 与 "Jay Chou" 不相等，这类名字靠别名匹配
+func RomanEqual(a, b string) bool {
+	va, vb := Romanizations(a), Romanizations(b)
+	for _, x := range va {
+		for _, y := range vb {
+			if romanRotationEqual(romanKey(x), y) || romanRotationEqual(romanKey(y), x) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// romanRotationEqual key 是否等于罗马字 roman 的某种词序轮换拼接后的结果
+func romanRotationEqual(key, roman string) bool {
+	if key == "" {
+		return false
+	}
+	words := strings.Fields(romanWords(roman))
+	for i := range words {
+		if strings.Join(append(slices.Clone(words[i:]), words[:i]...), "") == key {
+			return true
+		}
+	}
+	return false
+}
+
+// romanWords 与 romanKey 相同，但保留词之间的空格
+func romanWords(roman string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == ' ' {
+			return r
+		}
+		return ' '
+	}, roman)
+}
+
+// writeWord 转换出的罗马字前后用空格与相邻文字隔开
+func writeWord(b *strings.Builder, word string) {
+	b.WriteByte(' ')
+	b.WriteString(word)
+	b.WriteByte(' ')
+}
+
+// romanizeKana 转换一段连续的假名
+// 促音重复下一个辅音；和 Spotify 上常见的写法一样省略长音："とうきょう" 写作 "tokyo"
+func romanizeKana(kana []rune) string {
+	hiragana := make([]rune, len(kana))
+	for i, r := range kana {
+		if r >= 'ァ' && r <= 'ヶ' {
+			r -= 'ァ' - 'ぁ'
+		}
+		hiragana[i] = r
+	}
+
+	var b strings.Builder
+	double := false
+	for i := 0; i < len(hiragana); {
+		r := hiragana[i]
+		if r == 'っ' {
+			double = true
+			i++
+			continue
+		}
+		if r == 'ー' || r == '・' {
+			i++
+			continue
+		}
+
+		roman, size := "", 1
+		if i+1 < len(hiragana) {
+			if pair, ok := kanaTable[string(hiragana[i:i+2])]; ok {
+				roman, size = pair, 2
+			}
+		}
+		if roman == "" {
+			roman = kanaTable[string(r)]
+			// おう、うう、おお 中后一个元音是长音
+			if last := b.String(); (r == 'う' && (strings.HasSuffix(last, "o") || strings.HasSuffix(last, "u"))) ||
+				(r == 'お' && strings.HasSuffix(last, "o")) {
+				i++
+				continue
+			}
+		}
+		if double && roman != "" {
+			if strings.HasPrefix(roman, "ch") {
+				b.WriteByte('t')
+			} else {
+				b.WriteByte(roman[0])
+			}
+		}
+		double = false
+		b.WriteString(roman)
+		i += size
+	}
+	return b.String()
+}
+
+// romanizeHangul 转换一段连续的谚文音节
+func romanizeHangul(syllables []rune) string {
+	var b strings.Builder
+	for _, r := range syllables {
+		index := int(r - hangulBase)
+		b.WriteString(hangulInitials[index/hangulVowel])
+		b.WriteString(hangulMedials[index%hangulVowel/28])
+		b.WriteString(hangulFinals[index%28])
+	}
+	return b.String()
+}
+
+func isKana(r rune) bool {
+	return (r >= 'ぁ' && r <= 'ゖ') || (r >= 'ァ' && r <= 'ヺ') || r == 'ー'
+}
+
+func isCJK(r rune) bool {
+	return isHan(r) || isKana(r) || (r >= hangulBase && r <= hangulLast)
+}
@@ -0,0 +1,52 @@
+package normalize
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRomanizations(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"周杰伦", []string{"zhou jie lun"}},
+		{"周杰倫", []string{"zhou jie lun"}},
+		{"米津玄師", []string{"yonezu kenshi", "mi jin xuan shi"}},
+		{"よねづけんし", []string{"yonezukenshi"}},
+		{"宇多田ヒカル", []string{"utada hikaru"}},
+		{"夜に駆ける", []string{"yoru ni kakeru"}},
+		{"方大同", []string{"fang da tong", "kata daido"}},
+		{"방탄소년단", []string{"bangtansonyeondan"}},
+		{"Lemon", []string{"lemon"}},
+	}
+	for _, tt := range tests {
+		if got := Romanizations(tt.in); !slices.Equal(got, tt.want) {
+			t.Errorf("Romanizations(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRomanEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"周杰伦", "Zhou Jielun", true},
+		{"周杰伦", "Jielun Zhou", true},
+		// 英文艺名不是罗马字，交给别名匹配
+		{"周杰伦", "Jay Chou", false},
+		{"米津玄師", "Kenshi Yonezu", true},
+		{"米津玄師", "Yonezu Kenshi", true},
+		{"よねづけんし", "Kenshi Yonezu", true},
+		{"山下達郎", "Tatsuro Yamashita", true},
+		{"방탄소년단", "Bangtan Sonyeondan", true},
+		{"米津玄師", "Kenshi", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got := RomanEqual(tt.a, tt.b); got != tt.want {
+			t.Errorf("RomanEqual(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
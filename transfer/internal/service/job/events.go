@@ -0,0 +1,60 @@
+package job
+
+import (
+	"sync"
+	"transfer/internal/domain"
+)
+
+// 订阅者消费太慢时丢弃事件，事件里的累计数量会在下一条事件中追上
+const subscriberBuffer = 256
+
+// broker 把运行中任务的事件分发给订阅者
+type broker struct {
+	subscribers map[string]map[chan domain.TransferEvent]struct{}
+	mutex       sync.Mutex
+}
+
+func newBroker() *broker {
+	return &broker{
+		subscribers: make(map[string]map[chan domain.TransferEvent]struct{}),
+	}
+}
+
+func (b *broker) subscribe(jobID string) chan domain.TransferEvent {
+	ch := make(chan domain.TransferEvent, subscriberBuffer)
+	if b.subscribers[jobID] == nil {
+		b.subscribers[jobID] = make(map[chan domain.TransferEvent]struct{})
+	}
+	b.subscribers[jobID][ch] = struct{}{}
+	return ch
+}
+
+func (b *broker) unsubscribe(jobID string, ch chan domain.TransferEvent) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if _, ok := b.subscribers[jobID][ch]; ok {
+		delete(b.subscribers[jobID], ch)
+		close(ch)
+	}
+}
+
+func (b *broker) publish(jobID string, event domain.TransferEvent) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for ch := range b.subscribers[jobID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// closeJob 任务结束时关闭所有订阅，调用方需持有锁
+func (b *broker) closeJob(jobID string) {
+	for ch := range b.subscribers[jobID] {
+		close(ch)
+	}
+	delete(b.subscribers, jobID)
+}
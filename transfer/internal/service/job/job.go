@@ -0,0 +1,36 @@
+package job
+
+import (
+	"time"
+	"transfer/internal/domain"
+)
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// Job 一次异步迁移任务，完整状态可以持久化
+type Job struct {
+	ID         string                   `json:"id"`
+	UserID     string                   `json:"user_id"`
+	PlaylistID string                   `json:"playlist_id"`
+	Preview    bool                     `json:"preview,omitempty"` // 只匹配不写入，结果是待提交的计划
+	Versions   domain.VersionPreference `json:"versions"`          // 选择候选时对版本的偏好，续传时沿用
+	Tracks     []domain.Track           `json:"tracks"`
+	Status     Status                   `json:"status"`
+	Error      string                   `json:"error,omitempty"`
+	Result     *domain.TransferResult   `json:"result,omitempty"`
+	CreatedAt  time.Time                `json:"created_at"`
+	UpdatedAt  time.Time                `json:"updated_at"`
+}
+
+// Finished 任务是否已经结束，不会再被 worker 处理
+func (j *Job) Finished() bool {
+	return j.Status == StatusCompleted || j.Status == StatusFailed || j.Status == StatusCancelled
+}
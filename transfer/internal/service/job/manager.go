@@ -0,0 +1,472 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+	"transfer/internal/domain"
+	"transfer/internal/service"
+	"transfer/internal/service/review"
+	"transfer/internal/storage"
+
+	"github.com/zmb3/spotify"
+)
+
+const (
+	DefaultWorkers = 2
+	queueSize      = 256
+)
+
+var (
+	ErrQueueFull       = errors.New("job queue is full")
+	ErrJobNotFinished  = errors.New("job is still running")
+	ErrJobFinished     = errors.New("job has already finished")
+	ErrNothingToResume = errors.New("job has no unfinished tracks")
+	ErrNothingToRetry  = errors.New("job has no failed tracks")
+	ErrUnfinishedJob   = errors.New("job has unfinished tracks, resume it instead")
+	ErrNotPreview      = errors.New("job is not a preview")
+	ErrNotCancellable  = errors.New("running job has no cancel function")
+)
+
+// ClientProvider 按用户获取已授权的 Spotify 客户端
+type ClientProvider func(userID string) (spotify.Client, error)
+
+// Manager 接收迁移任务并交给固定数量的 worker 执行
+type Manager struct {
+	store   Store
+	svc     service.SpotifyService
+	reviews *review.Service
+	clients ClientProvider
+	queue   chan string
+	workers int
+	events  *broker
+
+	mutex   sync.Mutex                    // 保护任务状态的切换，保证同一个任务不会被重复排队
+	cancels map[string]context.CancelFunc // 运行中任务的取消函数
+}
+
+func NewManager(store Store, svc service.SpotifyService, reviews *review.Service, clients ClientProvider, workers int) *Manager {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	return &Manager{
+		store:   store,
+		svc:     svc,
+		reviews: reviews,
+		clients: clients,
+		queue:   make(chan string, queueSize),
+		workers: workers,
+		events:  newBroker(),
+		cancels: make(map[string]context.CancelFunc),
+	}
+}
+
+// Start 启动 worker，并接管上次进程退出时遗留的任务
+func (m *Manager) Start(ctx context.Context) error {
+	jobs, err := m.store.List()
+	if err != nil {
+		return fmt.Errorf("failed to load jobs: %w", err)
+	}
+
+	for i := 0; i < m.workers; i++ {
+		go m.work(ctx)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+
+	for _, job := range jobs {
+		switch job.Status {
+		case StatusPending:
+			m.queue <- job.ID
+		case StatusRunning:
+			// 执行到一半被中断，进度已经按歌曲保存，用户可以续传
+			m.applyReviews(job)
+			m.finish(job, nil, errors.New("interrupted by server restart"))
+		default:
+			// 确认结果写回之前进程退出时，在这里补上
+			if m.applyReviews(job) {
+				m.save(job)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Submit 创建任务并立即返回，实际迁移由 worker 异步完成
+func (m *Manager) Submit(userID, playlistID string, tracks []domain.Track, versions domain.VersionPreference) (*Job, error) {
+	job, err := newJob(userID, playlistID, service.NewTransferResult(tracks), versions)
+	if err != nil {
+		return nil, err
+	}
+	return m.enqueue(job)
+}
+
+// SubmitPreview 创建只匹配不写入的预览任务，完成后可以用 Commit 按计划迁移
+// 预览同样需要已经存在的目标歌单，新建歌单的流程（CreatePlaylist）不能先预览
+func (m *Manager) SubmitPreview(userID, playlistID string, tracks []domain.Track, versions domain.VersionPreference) (*Job, error) {
+	job, err := newJob(userID, playlistID, service.NewTransferResult(tracks), versions)
+	if err != nil {
+		return nil, err
+	}
+	job.Preview = true
+	return m.enqueue(job)
+}
+
+func newJob(userID, playlistID string, result *domain.TransferResult, versions domain.VersionPreference) (*Job, error) {
+	if playlistID == "" {
+		return nil, errors.New("playlist ID cannot be empty")
+	}
+
+	id, err := storage.NewID()
+	if err != nil {
+		return nil, err
+	}
+
+	tracks := make([]domain.Track, len(result.Records))
+	for i, record := range result.Records {
+		tracks[i] = record.Track
+	}
+
+	now := time.Now()
+	return &Job{
+		ID:         id,
+		UserID:     userID,
+		PlaylistID: playlistID,
+		Versions:   versions,
+		Tracks:     tracks,
+		Status:     StatusPending,
+		Result:     result,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}, nil
+}
+
+func (m *Manager) enqueue(job *Job) (*Job, error) {
+	if err := m.store.Save(job); err != nil {
+		return nil, err
+	}
+
+	select {
+	case m.queue <- job.ID:
+		return job, nil
+	default:
+		m.finish(job, nil, ErrQueueFull)
+		return nil, ErrQueueFull
+	}
+}
+
+// Commit 按预览的计划执行迁移，已匹配的歌曲直接添加，不再重新搜索
+func (m *Manager) Commit(id string) (*Job, error) {
+	return m.requeue(id, func(job *Job) error {
+		if !job.Preview {
+			return ErrNotPreview
+		}
+		job.Preview = false
+
+		// 预览不写入目标歌单，计划也可能不被提交，待确认的歌曲在提交时才进入确认队列
+		return m.reviews.Park(job.ID, job.UserID, job.PlaylistID, job.Result.NeedsReview)
+	})
+}
+
+// Resume 重新排队一个已结束的任务，从第一首未完成的歌曲继续
+func (m *Manager) Resume(id string) (*Job, error) {
+	return m.requeue(id, func(job *Job) error {
+		if !unfinished(job) {
+			return ErrNothingToResume
+		}
+		return nil
+	})
+}
+
+// unfinished 任务是否还有再次运行时会处理的歌曲
+// 预览只处理待搜索的歌曲，已匹配的留给 Commit
+func unfinished(job *Job) bool {
+	for _, record := range job.Result.Records {
+		if record.Status == domain.TrackPending || (record.Status == domain.TrackMatched && !job.Preview) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryFailed 重新排队一个已结束的任务，只重新处理失败的歌曲
+// 还有未处理的歌曲时（例如任务被取消）拒绝重试，否则这些歌曲也会被处理，应该用 Resume
+func (m *Manager) RetryFailed(id string) (*Job, error) {
+	return m.requeue(id, func(job *Job) error {
+		if unfinished(job) {
+			return ErrUnfinishedJob
+		}
+		if service.ResetFailed(job.Result) == 0 {
+			return ErrNothingToRetry
+		}
+		return nil
+	})
+}
+
+// requeue 在已有的迁移结果上重新排队，prepare 检查并调整结果
+func (m *Manager) requeue(id string, prepare func(job *Job) error) (*Job, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	job, err := m.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !job.Finished() {
+		return nil, ErrJobNotFinished
+	}
+	if job.Result == nil {
+		job.Result = service.NewTransferResult(job.Tracks)
+	}
+	if err := prepare(job); err != nil {
+		return nil, err
+	}
+
+	job.Status = StatusPending
+	job.Error = ""
+	job.UpdatedAt = time.Now()
+	return m.enqueue(job)
+}
+
+// Cancel 取消任务：排队中的直接结束，运行中的加完当前批次后停止
+func (m *Manager) Cancel(id string) (*Job, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	job, err := m.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case job.Finished():
+		return nil, ErrJobFinished
+	case job.Status == StatusPending:
+		if job.Result != nil {
+			job.Result.Status = domain.TransferCancelled
+		}
+		m.finish(job, nil, nil)
+	default:
+		cancel, ok := m.cancels[id]
+		if !ok {
+			return nil, ErrNotCancellable
+		}
+		cancel()
+	}
+	return job, nil
+}
+
+// ApplyReview 把确认结果写回来源任务的记录
+// 运行中的任务会继续保存自己的结果，这时不写入；确认结果已经由 reviews 持久化，
+// 任务结束时（包括重启后接管中断的任务时）由 applyReviews 统一写回
+func (m *Manager) ApplyReview(item review.Item) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	job, err := m.store.Get(item.JobID)
+	if err != nil {
+		log.Printf("job %s: failed to apply review %s: %v", item.JobID, item.ID, err)
+		return
+	}
+	if job.Status == StatusRunning {
+		return
+	}
+	if job.Result != nil && applyReview(job.Result, item) {
+		m.save(job)
+	}
+}
+
+// applyReviews 把该任务所有已处理的确认写回结果，已经写回过的记录不会重复修改
+// 返回结果是否有变化
+func (m *Manager) applyReviews(job *Job) bool {
+	if job.Result == nil {
+		return false
+	}
+	changed := false
+	for _, item := range m.reviews.Resolved(job.ID) {
+		changed = applyReview(job.Result, item) || changed
+	}
+	return changed
+}
+
+// applyReview 更新确认歌曲对应的记录，记录已不在待确认状态时不做改动
+func applyReview(result *domain.TransferResult, item review.Item) bool {
+	if item.Index < 0 || item.Index >= len(result.Records) {
+		return false
+	}
+	record := &result.Records[item.Index]
+	if record.Status != domain.TrackNeedsReview {
+		return false
+	}
+
+	switch item.Status {
+	case review.StatusApproved:
+		record.Status = domain.TrackAdded
+		record.SpotifyID = item.ChosenID
+		record.Match = nil
+		for _, c := range item.Candidates {
+			if c.SpotifyID == item.ChosenID {
+				record.Match = &c
+				record.Confidence = c.Confidence
+				break
+			}
+		}
+		result.SuccessCount++
+		result.SuccessTracks = append(result.SuccessTracks, item.ChosenID)
+	case review.StatusSkipped:
+		record.Status = domain.TrackSkipped
+	default:
+		return false
+	}
+	return true
+}
+
+func (m *Manager) Get(id string) (*Job, error) {
+	return m.store.Get(id)
+}
+
+// Subscribe 订阅任务的逐首进度事件，任务结束时 channel 会被关闭
+// 如果任务已经结束，返回的 channel 为 nil，调用方直接读取任务结果即可
+func (m *Manager) Subscribe(id string) (<-chan domain.TransferEvent, func(), error) {
+	m.events.mutex.Lock()
+	defer m.events.mutex.Unlock()
+
+	// 在锁内读取状态，保证不会错过 finish 发出的关闭
+	job, err := m.store.Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if job.Finished() {
+		return nil, func() {}, nil
+	}
+
+	ch := m.events.subscribe(id)
+	return ch, func() { m.events.unsubscribe(id, ch) }, nil
+}
+
+func (m *Manager) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-m.queue:
+			m.run(ctx, id)
+		}
+	}
+}
+
+func (m *Manager) run(ctx context.Context, id string) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	job, ok := m.start(id, cancel)
+	if !ok {
+		return
+	}
+
+	opts := service.TransferOptions{
+		OnProgress: func(result domain.TransferResult) {
+			job.Result = &result
+			m.save(job)
+		},
+		OnEvent: func(event domain.TransferEvent) {
+			m.events.publish(job.ID, event)
+		},
+		Versions: job.Versions,
+	}
+
+	if job.Result == nil {
+		job.Result = service.NewTransferResult(job.Tracks)
+	}
+	if job.Preview {
+		result, err := m.svc.PreviewTransfer(ctx, job.Result, opts)
+		m.complete(job, result, err)
+		return
+	}
+
+	client, err := m.clients(job.UserID)
+	if err != nil {
+		m.complete(job, nil, fmt.Errorf("failed to get spotify client: %w", err))
+		return
+	}
+
+	// 续传时之前的待确认歌曲已经交给了 reviews，只提交本次新增的
+	parked := len(job.Result.NeedsReview)
+
+	result, err := m.svc.ContinueTransfer(ctx, client, job.PlaylistID, job.Result, opts)
+	if result != nil {
+		if err := m.reviews.Park(job.ID, job.UserID, job.PlaylistID, result.NeedsReview[parked:]); err != nil {
+			log.Printf("job %s: failed to queue reviews: %v", job.ID, err)
+		}
+	}
+	m.complete(job, result, err)
+}
+
+// complete 结束 worker 执行的任务，写回运行期间完成的确认
+// 取消函数在同一把锁内、任务结束之前移除，任务一旦结束就可能被重新排队并登记新的取消函数
+func (m *Manager) complete(job *Job, result *domain.TransferResult, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.cancels, job.ID)
+
+	if result != nil {
+		job.Result = result
+	}
+	m.applyReviews(job)
+	m.finish(job, nil, err)
+}
+
+// start 把排队中的任务切换为运行中，并登记取消函数
+func (m *Manager) start(id string, cancel context.CancelFunc) (*Job, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	job, err := m.store.Get(id)
+	if err != nil {
+		log.Printf("job %s: failed to load: %v", id, err)
+		return nil, false
+	}
+	if job.Status != StatusPending {
+		return nil, false
+	}
+
+	job.Status = StatusRunning
+	m.save(job)
+	m.cancels[id] = cancel
+	return job, true
+}
+
+func (m *Manager) finish(job *Job, result *domain.TransferResult, err error) {
+	if result != nil {
+		job.Result = result
+	}
+	switch {
+	case err != nil:
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	case job.Result != nil && job.Result.Status == domain.TransferCancelled:
+		job.Status = StatusCancelled
+	default:
+		job.Status = StatusCompleted
+	}
+	m.save(job)
+
+	m.events.mutex.Lock()
+	m.events.closeJob(job.ID)
+	m.events.mutex.Unlock()
+}
+
+func (m *Manager) save(job *Job) {
+	job.UpdatedAt = time.Now()
+	if err := m.store.Save(job); err != nil {
+		log.Printf("job %s: failed to save: %v", job.ID, err)
+	}
+}
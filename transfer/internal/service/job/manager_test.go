@@ -0,0 +1,323 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"transfer/internal/domain"
+	"transfer/internal/service"
+	"transfer/internal/service/ratelimit"
+	"transfer/internal/service/review"
+	"transfer/internal/spotifytest"
+
+	"github.com/zmb3/spotify"
+)
+
+const testPlaylist = "playlist"
+
+// newTestManager 创建使用 spotifytest 的任务管理器，任务和确认队列保存在临时目录中
+func newTestManager(t *testing.T, api *spotifytest.API, searchWorkers int) *Manager {
+	t.Helper()
+
+	scheduler := ratelimit.New(ratelimit.Config{RequestsPerSecond: 1000, Burst: 1000})
+	client := api.Client()
+	matcher := service.NewSpotifyMatcher(client, scheduler, service.MatcherConfig{ReviewThreshold: 0.6})
+	svc := service.NewSpotifyService(client, matcher, scheduler, searchWorkers)
+
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reviewStore, err := review.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reviews, err := review.NewService(reviewStore, scheduler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewManager(store, svc, reviews, func(string) (spotify.Client, error) { return client, nil }, 1)
+	reviews.OnResolved(m.ApplyReview)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := m.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// catalog 按字段限定查询中的歌名返回对应的歌曲，其余查询没有结果
+func catalog(tracks ...spotify.FullTrack) func(query string) []spotify.FullTrack {
+	return func(query string) []spotify.FullTrack {
+		for _, track := range tracks {
+			if strings.Contains(query, `track:"`+track.Name+`"`) {
+				return []spotify.FullTrack{track}
+			}
+		}
+		return nil
+	}
+}
+
+func sourceTracks(names ...string) []domain.Track {
+	tracks := make([]domain.Track, len(names))
+	for i, name := range names {
+		tracks[i] = domain.Track{Title: name, Artist: "Band", DurationMs: 200000}
+	}
+	return tracks
+}
+
+// waitFinished 等待任务结束并返回最终状态
+func waitFinished(t *testing.T, m *Manager, id string) *Job {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := m.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Finished() {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return nil
+}
+
+func statuses(job *Job) []domain.TrackStatus {
+	var result []domain.TrackStatus
+	for _, record := range job.Result.Records {
+		result = append(result, record.Status)
+	}
+	return result
+}
+
+// 运行中取消：正在搜索的歌曲完成后加完当前批次，其余歌曲保持待处理，续传时只处理这些歌曲
+func TestCancelThenResume(t *testing.T) {
+	api := spotifytest.New()
+	lookup := catalog(
+		spotifytest.Track("a", "Alpha", "Band", 200000),
+		spotifytest.Track("b", "Beta", "Band", 200000),
+		spotifytest.Track("c", "Gamma", "Band", 200000),
+	)
+	// 第一次搜索停住，直到任务被取消；同一批内的搜索没有固定的先后顺序
+	blocked := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	api.Search = func(query string) []spotify.FullTrack {
+		once.Do(func() {
+			close(blocked)
+			<-release
+		})
+		return lookup(query)
+	}
+	m := newTestManager(t, api, 1)
+
+	job, err := m.Submit("user", testPlaylist, sourceTracks("Alpha", "Beta", "Gamma"), domain.VersionPreference{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	<-blocked
+	if _, err := m.Cancel(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+
+	job = waitFinished(t, m, job.ID)
+	counts := make(map[domain.TrackStatus]int)
+	for _, status := range statuses(job) {
+		counts[status]++
+	}
+	if job.Status != StatusCancelled || counts[domain.TrackAdded] != 1 || counts[domain.TrackPending] != 2 {
+		t.Fatalf("after cancel: job %s, tracks %v, want cancelled with 1 added and 2 pending", job.Status, statuses(job))
+	}
+	if got := api.Playlist(testPlaylist); len(got) != 1 {
+		t.Fatalf("playlist after cancel = %v, want the searched track only", got)
+	}
+
+	// 还有未处理的歌曲时只能续传，不能只重试失败的歌曲
+	if _, err := m.RetryFailed(job.ID); !errors.Is(err, ErrUnfinishedJob) {
+		t.Fatalf("RetryFailed on cancelled job: err = %v, want %v", err, ErrUnfinishedJob)
+	}
+
+	if _, err := m.Resume(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	job = waitFinished(t, m, job.ID)
+	if job.Status != StatusCompleted || job.Result.SuccessCount != 3 {
+		t.Fatalf("after resume: job %s with %d added, want completed with 3", job.Status, job.Result.SuccessCount)
+	}
+	got := api.Playlist(testPlaylist)
+	slices.Sort(got)
+	if !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("playlist after resume = %v, want a, b and c once each", got)
+	}
+	if _, err := m.Resume(job.ID); !errors.Is(err, ErrNothingToResume) {
+		t.Errorf("Resume on completed job: err = %v, want %v", err, ErrNothingToResume)
+	}
+}
+
+// 只重试失败的歌曲：第一次搜不到的歌曲重试时重新搜索，已经添加的歌曲不再处理
+func TestRetryFailed(t *testing.T) {
+	api := spotifytest.New()
+	lookup := catalog(
+		spotifytest.Track("a", "Alpha", "Band", 200000),
+		spotifytest.Track("b", "Beta", "Band", 200000),
+		spotifytest.Track("c", "Gamma", "Band", 200000),
+	)
+	var mutex sync.Mutex
+	available := false
+	api.Search = func(query string) []spotify.FullTrack {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if strings.Contains(query, "Beta") && !available {
+			return nil
+		}
+		return lookup(query)
+	}
+	m := newTestManager(t, api, 2)
+
+	job, err := m.Submit("user", testPlaylist, sourceTracks("Alpha", "Beta", "Gamma"), domain.VersionPreference{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job = waitFinished(t, m, job.ID)
+	want := []domain.TrackStatus{domain.TrackAdded, domain.TrackFailed, domain.TrackAdded}
+	if !slices.Equal(statuses(job), want) || len(job.Result.FailedTracks) != 1 || job.Result.FailedTracks[0].Index != 1 {
+		t.Fatalf("first run: tracks %v, failed %v, want %v with Beta failed", statuses(job), job.Result.FailedTracks, want)
+	}
+
+	mutex.Lock()
+	available = true
+	mutex.Unlock()
+	searches := len(api.Queries())
+
+	if _, err := m.RetryFailed(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	job = waitFinished(t, m, job.ID)
+	want = []domain.TrackStatus{domain.TrackAdded, domain.TrackAdded, domain.TrackAdded}
+	if job.Status != StatusCompleted || !slices.Equal(statuses(job), want) || len(job.Result.FailedTracks) != 0 {
+		t.Fatalf("after retry: job %s, tracks %v, failed %v, want completed with %v", job.Status, statuses(job), job.Result.FailedTracks, want)
+	}
+	for _, query := range api.Queries()[searches:] {
+		if !strings.Contains(query, "Beta") {
+			t.Errorf("retry searched %q, want only the failed track", query)
+		}
+	}
+	if got := api.Playlist(testPlaylist); !slices.Equal(got, []string{"a", "c", "b"}) {
+		t.Errorf("playlist = %v, want [a c b]", got)
+	}
+	if _, err := m.RetryFailed(job.ID); !errors.Is(err, ErrNothingToRetry) {
+		t.Errorf("RetryFailed without failures: err = %v, want %v", err, ErrNothingToRetry)
+	}
+}
+
+// 歌单中已有的歌曲以及本次迁移中重复匹配到的歌曲不再添加，同一迁移再运行一次不会产生重复
+func TestSkipsTracksAlreadyPresent(t *testing.T) {
+	api := spotifytest.New()
+	api.Search = catalog(
+		spotifytest.Track("a", "Alpha", "Band", 200000),
+		spotifytest.Track("b", "Beta", "Band", 200000),
+		spotifytest.Track("c", "Gamma", "Band", 200000),
+	)
+	api.SetPlaylist(testPlaylist, "b")
+	m := newTestManager(t, api, 2)
+	tracks := sourceTracks("Alpha", "Beta", "Gamma", "Alpha")
+
+	job, err := m.Submit("user", testPlaylist, tracks, domain.VersionPreference{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job = waitFinished(t, m, job.ID)
+	want := []domain.TrackStatus{domain.TrackAdded, domain.TrackAlreadyPresent, domain.TrackAdded, domain.TrackAlreadyPresent}
+	if !slices.Equal(statuses(job), want) || job.Result.SuccessCount != 2 || len(job.Result.AlreadyPresent) != 2 {
+		t.Fatalf("tracks %v, %d added, %d already present, want %v", statuses(job), job.Result.SuccessCount, len(job.Result.AlreadyPresent), want)
+	}
+	if got := api.Playlist(testPlaylist); !slices.Equal(got, []string{"b", "a", "c"}) {
+		t.Fatalf("playlist = %v, want [b a c]", got)
+	}
+
+	job, err = m.Submit("user", testPlaylist, tracks, domain.VersionPreference{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job = waitFinished(t, m, job.ID)
+	if job.Result.SuccessCount != 0 || len(job.Result.AlreadyPresent) != 4 {
+		t.Errorf("second run: %d added, %d already present, want 0 and 4", job.Result.SuccessCount, len(job.Result.AlreadyPresent))
+	}
+	if got := api.Playlist(testPlaylist); !slices.Equal(got, []string{"b", "a", "c"}) {
+		t.Errorf("playlist after second run = %v, want [b a c]", got)
+	}
+}
+
+// 并发搜索时按源歌单顺序添加；搜索失败和添加失败都只记在对应的歌曲上
+func TestKeepsSourceOrderAndFailureAttribution(t *testing.T) {
+	names := []string{"One", "Two", "Three", "Four", "Five", "Six"}
+	var catalogTracks []spotify.FullTrack
+	for _, name := range names {
+		if name != "Three" {
+			catalogTracks = append(catalogTracks, spotifytest.Track(strings.ToLower(name), name, "Band", 200000))
+		}
+	}
+	lookup := catalog(catalogTracks...)
+
+	api := spotifytest.New()
+	api.Search = func(query string) []spotify.FullTrack {
+		// 越靠前的歌曲搜索越慢，并发时完成的顺序与源歌单相反
+		for i, name := range names {
+			if strings.Contains(query, name) {
+				time.Sleep(time.Duration(len(names)-i) * 5 * time.Millisecond)
+			}
+		}
+		return lookup(query)
+	}
+	m := newTestManager(t, api, 4)
+
+	job, err := m.Submit("user", testPlaylist, sourceTracks(names...), domain.VersionPreference{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job = waitFinished(t, m, job.ID)
+	if got := api.Playlist(testPlaylist); !slices.Equal(got, []string{"one", "two", "four", "five", "six"}) {
+		t.Errorf("playlist = %v, want source order without three", got)
+	}
+	if !slices.Equal(job.Result.SuccessTracks, []string{"one", "two", "four", "five", "six"}) {
+		t.Errorf("success tracks = %v, want source order", job.Result.SuccessTracks)
+	}
+	if failed := job.Result.FailedTracks; len(failed) != 1 || failed[0].Index != 2 {
+		t.Errorf("failed tracks = %v, want only index 2", failed)
+	}
+
+	// 添加失败时只有本批要添加的歌曲失败，搜索失败的歌曲保留原来的原因
+	api.SetPlaylist(testPlaylist)
+	api.Intercept = func(req *http.Request) *http.Response {
+		if req.Method == http.MethodPost {
+			return spotifytest.Respond(http.StatusForbidden, nil)
+		}
+		return nil
+	}
+	job, err = m.Submit("user", testPlaylist, sourceTracks(names...), domain.VersionPreference{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job = waitFinished(t, m, job.ID)
+	if len(job.Result.FailedTracks) != len(names) || job.Result.SuccessCount != 0 {
+		t.Fatalf("failed %d, added %d, want all %d failed", len(job.Result.FailedTracks), job.Result.SuccessCount, len(names))
+	}
+	for _, record := range job.Result.Records {
+		addFailed := strings.HasPrefix(record.Error, "failed to add to playlist")
+		if addFailed != (record.SpotifyID != "") {
+			t.Errorf("track %d (%s): error %q, spotify ID %q", record.Index, record.Track.Title, record.Error, record.SpotifyID)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package job
+
+import (
+	"errors"
+	"transfer/internal/storage"
+)
+
+var ErrJobNotFound = errors.New("job not found")
+
+// Store 任务持久化接口
+type Store interface {
+	Save(job *Job) error
+	Get(id string) (*Job, error)
+	List() ([]*Job, error)
+}
+
+// FileStore 每个任务一个 JSON 文件，服务重启后任务状态仍然可读
+type FileStore struct {
+	dir *storage.Dir[Job]
+}
+
+func NewFileStore(dir string) (Store, error) {
+	d, err := storage.NewDir[Job](dir)
+	if err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: d}, nil
+}
+
+func (f *FileStore) Save(job *Job) error {
+	return f.dir.Save(job.ID, job)
+}
+
+func (f *FileStore) Get(id string) (*Job, error) {
+	job, err := f.dir.Get(id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrJobNotFound
+	}
+	return job, err
+}
+
+func (f *FileStore) List() ([]*Job, error) {
+	return f.dir.List()
+}
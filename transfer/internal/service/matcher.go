@@ -0,0 +1,476 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"transfer/internal/domain"
+	"transfer/internal/normalize"
+	"transfer/internal/service/ratelimit"
+
+	"github.com/zmb3/spotify"
+)
+
+const (
+	// 未配置候选数量时的默认值，其余默认值统一在 config 中设置
+	DefaultCandidateLimit = 5
+
+	// 时长相差超过这个窗口，时长相似度记为 0
+	durationWindowMs = 30_000
+
+	// 标题和艺术家各自最多使用的别名数量，避免别名多时查询数量膨胀
+	maxAliasQueries = 2
+)
+
+// 各项相似度的权重，缺失的项不参与加权，剩余项按比例放大
+const (
+	titleWeight    = 0.45
+	artistWeight   = 0.35
+	albumWeight    = 0.10
+	durationWeight = 0.10
+)
+
+// Matcher 为源歌曲在 Spotify 上挑选最佳匹配
+type Matcher interface {
+	// Match 搜索并为候选打分，versions 是本次迁移对版本的偏好
+	Match(ctx context.Context, track domain.Track, versions domain.VersionPreference) (*MatchResult, error)
+}
+
+// MatchResult 匹配结果，Candidates 按置信度从高到低排列
+type MatchResult struct {
+	Best        domain.Candidate
+	Candidates  []domain.Candidate
+	NeedsReview bool // 最佳候选的置信度低于阈值
+}
+
+type MatcherConfig struct {
+	CandidateLimit  int     // 每次搜索取回的候选数量
+	ReviewThreshold float64 // 低于该置信度的匹配需要人工确认
+	// 时长相差超过该值的候选不会被自动采用，0 表示不限制
+	DurationToleranceMs int
+}
+
+// spotifyMatcher 通过 Spotify 搜索取回多个候选并逐一打分
+type spotifyMatcher struct {
+	client    spotify.Client
+	scheduler *ratelimit.Scheduler
+	config    MatcherConfig
+}
+
+func NewSpotifyMatcher(client spotify.Client, scheduler *ratelimit.Scheduler, config MatcherConfig) Matcher {
+	if config.CandidateLimit <= 0 {
+		config.CandidateLimit = DefaultCandidateLimit
+	}
+	return &spotifyMatcher{
+		client:    client,
+		scheduler: scheduler,
+		config:    config,
+	}
+}
+
+func (m *spotifyMatcher) Match(ctx context.Context, track domain.Track, versions domain.VersionPreference) (*MatchResult, error) {
+	if track.Title == "" {
+		return nil, errors.New("track title cannot be empty")
+	}
+
+	// 依次尝试每条查询，最佳候选足够可信时立即采用；
+	// 都不够可信时返回最佳候选置信度最高的一次结果，交给用户确认
+	var best *MatchResult
+	for _, query := range searchQueries(track) {
+		found, err := m.search(ctx, track, query)
+		if err != nil {
+			return nil, err
+		}
+		if len(found) == 0 {
+			continue
+		}
+
+		result := m.rank(track, found, query.strategy, versions)
+		if !result.NeedsReview {
+			return result, nil
+		}
+		if best == nil || result.Best.Confidence > best.Best.Confidence {
+			best = result
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("no results found for track: %s by %s", track.Title, track.Artist)
+	}
+	return best, nil
+}
+
+// rank 为一次查询的结果打分排序，并判断最佳候选是否需要确认
+func (m *spotifyMatcher) rank(track domain.Track, found []spotify.FullTrack, strategy domain.MatchStrategy, versions domain.VersionPreference) *MatchResult {
+	candidates := make([]domain.Candidate, 0, len(found))
+	for _, t := range found {
+		candidate := scoreCandidate(track, t, versions)
+		candidate.Strategy = strategy
+		candidates = append(candidates, candidate)
+	}
+
+	// 时长超出容差的候选排在最后；同分时时长更接近的优先，
+	// 用来区分同名的电台版、专辑版和加长混音，其余情况保留 Spotify 的原始排名
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if inA, inB := m.withinTolerance(track, a), m.withinTolerance(track, b); inA != inB {
+			return inA
+		}
+		if a.Confidence != b.Confidence {
+			return a.Confidence > b.Confidence
+		}
+		return durationDiff(track, a) < durationDiff(track, b)
+	})
+
+	// 时长对不上的一律需要确认
+	best := candidates[0]
+	return &MatchResult{
+		Best:        best,
+		Candidates:  candidates,
+		NeedsReview: best.Confidence < m.config.ReviewThreshold || !m.withinTolerance(track, best),
+	}
+}
+
+// withinTolerance 候选的时长是否在容差之内，任一方缺少时长时不做限制
+func (m *spotifyMatcher) withinTolerance(track domain.Track, candidate domain.Candidate) bool {
+	return m.config.DurationToleranceMs <= 0 || durationDiff(track, candidate) <= m.config.DurationToleranceMs
+}
+
+// durationDiff 源歌曲与候选的时长差，任一方缺少时长时为 0
+func durationDiff(track domain.Track, candidate domain.Candidate) int {
+	if track.DurationMs <= 0 || candidate.DurationMs <= 0 {
+		return 0
+	}
+	return max(track.DurationMs-candidate.DurationMs, candidate.DurationMs-track.DurationMs)
+}
+
+// search 执行一条查询，返回 Spotify 给出的候选
+func (m *spotifyMatcher) search(ctx context.Context, track domain.Track, query searchQuery) ([]spotify.FullTrack, error) {
+	limit := m.config.CandidateLimit
+	var resp *spotify.SearchResult
+	err := m.scheduler.Do(ctx, func() (err error) {
+		resp, err = m.client.SearchOpt(query.text, spotify.SearchTypeTrack, &spotify.Options{
+			Limit: &limit,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("search failed for track %s: %w", track.Title, err)
+	}
+
+	if resp.Tracks == nil {
+		return nil, nil
+	}
+	return resp.Tracks.Tracks, nil
+}
+
+// searchQuery 一条搜索查询及其对应的搜索方式
+type searchQuery struct {
+	text     string
+	strategy domain.MatchStrategy
+}
+
+// buildSearchQuery 构建搜索查询字符串
+// 好品味：将复杂的字符串构建逻辑隔离
+// 标题去掉括号注释，艺术家只取第一位，多位艺术家拼在一起的字段限定几乎搜不到结果
+func buildSearchQuery(track domain.Track) string {
+	return fieldQuery(searchTitle(track), searchArtist(track))
+}
+
+// fieldQuery 带 track: 和 artist: 字段限定的查询，空的部分省略
+func fieldQuery(title, artist string) string {
+	var parts []string
+
+	if title != "" {
+		parts = append(parts, fmt.Sprintf("track:\"%s\"", title))
+	}
+
+	if artist != "" {
+		parts = append(parts, fmt.Sprintf("artist:\"%s\"", artist))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// buildLooseQuery 不带字段限定的查询
+func buildLooseQuery(track domain.Track) string {
+	return strings.TrimSpace(searchTitle(track) + " " + searchArtist(track))
+}
+
+// searchQueries 按顺序尝试的查询，前一个没有找到可信的匹配时才用下一个
+// 原名之后依次尝试标题和艺术家的别名、译名的组合
+// 含有中日韩文字时再用罗马字版本的艺术家和标题搜索，Spotify 上的艺术家常用罗马字登记
+// 不带字段限定的查询几乎总有结果，放在最后兜底
+func searchQueries(track domain.Track) []searchQuery {
+	var queries []searchQuery
+	add := func(strategy domain.MatchStrategy, texts ...string) {
+		for _, text := range texts {
+			queries = append(queries, searchQuery{text: text, strategy: strategy})
+		}
+	}
+
+	add(domain.MatchByText, buildSearchQuery(track))
+
+	// 别名只和原名搭配，不做两两组合；两边都有别名时再补一条都用第一个别名的查询
+	title, artist := searchTitle(track), searchArtist(track)
+	titleAliases := distinctAliases(title, searchTitleAliases(track))
+	artistAliases := distinctAliases(artist, searchArtistAliases(track))
+	for _, t := range titleAliases {
+		add(domain.MatchByAlias, fieldQuery(t, artist))
+	}
+	for _, a := range artistAliases {
+		add(domain.MatchByAlias, fieldQuery(title, a))
+	}
+	if len(titleAliases) > 0 && len(artistAliases) > 0 {
+		add(domain.MatchByAlias, fieldQuery(titleAliases[0], artistAliases[0]))
+	}
+
+	if normalize.HasCJK(artist) {
+		// 只有汉字的名字分不清是中文还是日语，两种读法各试一次字段限定的查询
+		for _, romanArtist := range normalize.Romanizations(artist) {
+			add(domain.MatchByRomanized, fieldQuery(title, romanArtist))
+		}
+		add(domain.MatchByRomanized, strings.TrimSpace(title+" "+normalize.Romanize(artist)))
+	}
+	if normalize.HasCJK(title) {
+		add(domain.MatchByRomanized, strings.TrimSpace(normalize.Romanize(title)+" "+normalize.Romanize(artist)))
+	}
+	add(domain.MatchByText, buildLooseQuery(track))
+
+	// 去掉空查询和重复的查询，保留第一次出现的顺序
+	seen := make(map[string]bool, len(queries))
+	return slices.DeleteFunc(queries, func(q searchQuery) bool {
+		if q.text == "" || seen[q.text] {
+			return true
+		}
+		seen[q.text] = true
+		return false
+	})
+}
+
+// distinctAliases 按比较形式去掉与原名或彼此重复的别名，最多保留 maxAliasQueries 个
+func distinctAliases(original string, aliases []string) []string {
+	seen := map[string]bool{normalize.Key(original): true}
+	result := make([]string, 0, min(len(aliases), maxAliasQueries))
+	for _, alias := range aliases {
+		key := normalize.Key(alias)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, alias)
+		if len(result) == maxAliasQueries {
+			break
+		}
+	}
+	return result
+}
+
+func searchTitle(track domain.Track) string {
+	return stripQuotes(normalize.ParseTitle(track.Title).Name)
+}
+
+func searchArtist(track domain.Track) string {
+	if artists := normalize.SplitArtists(track.Artist); len(artists) > 0 {
+		return stripQuotes(artists[0])
+	}
+	return ""
+}
+
+func searchTitleAliases(track domain.Track) []string {
+	titles := make([]string, 0, len(track.Aliases))
+	for _, alias := range track.Aliases {
+		titles = append(titles, stripQuotes(normalize.ParseTitle(alias).Name))
+	}
+	return titles
+}
+
+// searchArtistAliases 第一位艺术家的别名，与 searchArtist 一样只取第一位
+func searchArtistAliases(track domain.Track) []string {
+	if len(track.Artists) == 0 {
+		return nil
+	}
+	artists := make([]string, 0, len(track.Artists[0].Aliases))
+	for _, alias := range track.Artists[0].Aliases {
+		artists = append(artists, stripQuotes(normalize.Fold(alias)))
+	}
+	return artists
+}
+
+// stripQuotes 去掉会破坏字段限定的双引号
+func stripQuotes(s string) string {
+	return strings.TrimSpace(strings.ReplaceAll(s, `"`, " "))
+}
+
+// scoreCandidate 计算候选歌曲与源歌曲的相似度，版本不符合偏好时打折扣
+func scoreCandidate(track domain.Track, candidate spotify.FullTrack, versions domain.VersionPreference) domain.Candidate {
+	artists := make([]string, 0, len(candidate.Artists))
+	for _, a := range candidate.Artists {
+		artists = append(artists, a.Name)
+	}
+
+	// 别名和译名与原名等价，取相似度最高的一个
+	score := domain.ScoreBreakdown{
+		Title: bestSimilarity(append([]string{track.Title}, track.Aliases...), candidate.Name, titleSimilarity),
+	}
+	if track.Artist != "" {
+		score.Artist = ptr(artistSimilarity(sourceArtists(track), artists))
+	}
+	if track.Album != "" {
+		score.Album = ptr(bestSimilarity(append([]string{track.Album}, track.AlbumAliases...), candidate.Album.Name, textSimilarity))
+	}
+	if track.DurationMs > 0 && candidate.Duration > 0 {
+		score.Duration = ptr(durationSimilarity(track.DurationMs, candidate.Duration))
+	}
+
+	version := classifyVersion(candidate.Name)
+	if !versionMatches(wantedVersion(classifyVersion(track.Title), versions), version, versions) {
+		score.Version = ptr(versionPenalty)
+	}
+
+	return domain.Candidate{
+		SpotifyID:  string(candidate.ID),
+		Name:       candidate.Name,
+		Artists:    artists,
+		Album:      candidate.Album.Name,
+		DurationMs: candidate.Duration,
+		Confidence: confidence(score),
+		Score:      score,
+		Version:    version,
+	}
+}
+
+// confidence 按权重合并各项相似度，再乘以版本折扣
+func confidence(score domain.ScoreBreakdown) float64 {
+	total := titleWeight * score.Title
+	weights := titleWeight
+
+	parts := []struct {
+		value  *float64
+		weight float64
+	}{
+		{score.Artist, artistWeight},
+		{score.Album, albumWeight},
+		{score.Duration, durationWeight},
+	}
+	for _, p := range parts {
+		if p.value != nil {
+			total += p.weight * *p.value
+			weights += p.weight
+		}
+	}
+
+	result := total / weights
+	if score.Version != nil {
+		result *= *score.Version
+	}
+	return math.Round(result*1000) / 1000
+}
+
+// artistSimilarity 源歌曲的每位艺术家（含别名）取与候选艺术家的最高相似度，再求平均
+func artistSimilarity(source [][]string, candidate []string) float64 {
+	if len(source) == 0 || len(candidate) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, names := range source {
+		var best float64
+		for _, c := range candidate {
+			best = math.Max(best, bestSimilarity(names, c, textSimilarity))
+		}
+		sum += best
+	}
+	return sum / float64(len(source))
+}
+
+// bestSimilarity names 中与 candidate 最相似的一个的相似度
+func bestSimilarity(names []string, candidate string, similarity func(a, b string) float64) float64 {
+	var best float64
+	for _, name := range names {
+		best = math.Max(best, similarity(name, candidate))
+	}
+	return best
+}
+
+func durationSimilarity(sourceMs, candidateMs int) float64 {
+	diff := math.Abs(float64(sourceMs - candidateMs))
+	return math.Max(0, 1-diff/durationWindowMs)
+}
+
+// titleSimilarity 去掉两边的括号注释后比较标题
+func titleSimilarity(source, candidate string) float64 {
+	return textSimilarity(normalize.ParseTitle(source).Name, normalize.ParseTitle(candidate).Name)
+}
+
+// textSimilarity 基于编辑距离的相似度，1 表示完全相同
+// 比较前统一全半角、繁简、标点和大小写，并认可罗马字相同的写法
+func textSimilarity(a, b string) float64 {
+	// 一边是中日韩文字、另一边是罗马字时，罗马字相同视为同一个名字
+	if normalize.HasCJK(a) != normalize.HasCJK(b) && normalize.RomanEqual(a, b) {
+		return 1
+	}
+
+	ra := []rune(normalize.Key(a))
+	rb := []rune(normalize.Key(b))
+
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// sourceArtists 源歌曲的艺术家，每位一组名字，第一个是原名，其余是别名
+// 有逐位艺术家信息时直接使用，否则拆分 Artist；最后不重复地加上从标题中提取的合作艺术家
+func sourceArtists(track domain.Track) [][]string {
+	var artists [][]string
+	if len(track.Artists) > 0 {
+		for _, a := range track.Artists {
+			artists = append(artists, append([]string{a.Name}, a.Aliases...))
+		}
+	} else {
+		for _, name := range normalize.SplitArtists(track.Artist) {
+			artists = append(artists, []string{name})
+		}
+	}
+
+	for _, featured := range track.FeaturedArtists {
+		known := slices.ContainsFunc(artists, func(names []string) bool {
+			return slices.ContainsFunc(names, func(a string) bool { return normalize.Key(a) == normalize.Key(featured) })
+		})
+		if !known {
+			artists = append(artists, []string{featured})
+		}
+	}
+	return artists
+}
+
+func ptr(v float64) *float64 {
+	return &v
+}
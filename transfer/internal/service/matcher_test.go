@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"transfer/internal/domain"
+	"transfer/internal/service/ratelimit"
+	"transfer/internal/spotifytest"
+
+	"github.com/zmb3/spotify"
+)
+
+func newTestMatcher(api *spotifytest.API) Matcher {
+	scheduler := ratelimit.New(ratelimit.Config{RequestsPerSecond: 1000, Burst: 1000})
+	return NewSpotifyMatcher(api.Client(), scheduler, MatcherConfig{ReviewThreshold: 0.6})
+}
+
+// 字段限定的原名查询只搜到无关的翻唱时，继续尝试罗马字查询，而不是停在第一个有结果的查询
+func TestMatchFallsBackToRomanized(t *testing.T) {
+	api := spotifytest.New()
+	api.Search = func(query string) []spotify.FullTrack {
+		switch query {
+		case `track:"Lemon" artist:"米津玄師"`:
+			return []spotify.FullTrack{spotifytest.Track("cover", "Lemon (Cover)", "Piano Covers", 255000)}
+		case `track:"Lemon" artist:"yonezu kenshi"`:
+			return []spotify.FullTrack{spotifytest.Track("lemon", "Lemon", "Kenshi Yonezu", 255000)}
+		}
+		return nil
+	}
+
+	track := domain.Track{Title: "Lemon", Artist: "米津玄師", DurationMs: 255000}
+	result, err := newTestMatcher(api).Match(context.Background(), track, domain.VersionPreference{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Best.SpotifyID != "lemon" || result.Best.Strategy != domain.MatchByRomanized || result.NeedsReview {
+		t.Errorf("best = %s (%s, review %v), want lemon by romanized query", result.Best.SpotifyID, result.Best.Strategy, result.NeedsReview)
+	}
+	if queries := api.Queries(); slices.Contains(queries, "Lemon 米津玄師") {
+		t.Errorf("loose query issued before a confident match was found: %q", queries)
+	}
+}
+
+// 所有查询都不够可信时，返回置信度最高的结果并交给用户确认，不带字段限定的查询最后才尝试
+func TestMatchKeepsBestUncertainResult(t *testing.T) {
+	api := spotifytest.New()
+	api.Search = func(query string) []spotify.FullTrack {
+		if strings.Contains(query, "artist:") {
+			return nil
+		}
+		return []spotify.FullTrack{spotifytest.Track("other", "Lemonade", "Someone Else", 180000)}
+	}
+
+	track := domain.Track{Title: "Lemon", Artist: "米津玄師", DurationMs: 255000}
+	result, err := newTestMatcher(api).Match(context.Background(), track, domain.VersionPreference{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Best.SpotifyID != "other" || !result.NeedsReview {
+		t.Errorf("best = %s (review %v), want other needing review", result.Best.SpotifyID, result.NeedsReview)
+	}
+	queries := api.Queries()
+	if last := queries[len(queries)-1]; last != "Lemon 米津玄師" {
+		t.Errorf("last query = %q, want the loose query; all queries: %q", last, queries)
+	}
+}
+
+// 网易云的译名（tns）和别名（alias）会变成别名查询，原名搜不到时用它们找到 Spotify 上的英文名
+func TestMatchSearchesNeteaseAliases(t *testing.T) {
+	const body = `{"code":200,"playlist":{"id":1,"name":"test","tracks":[{
+		"id":186001,"name":"晴天","dt":269000,"alia":[],"tns":["Sunny Day"],
+		"ar":[{"id":6452,"name":"周杰伦","alias":["Jay Chou"],"tns":[]}],
+		"al":{"name":"叶惠美","tns":[]}}]}}`
+	var resp PlaylistResponse
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	track := (&neteaseService{}).convertToMusicList(&resp).Tracks[0]
+
+	api := spotifytest.New()
+	api.Search = func(query string) []spotify.FullTrack {
+		if query == `track:"晴天" artist:"Jay Chou"` {
+			return []spotify.FullTrack{spotifytest.Track("qingtian", "晴天", "Jay Chou", 269000)}
+		}
+		return nil
+	}
+
+	result, err := newTestMatcher(api).Match(context.Background(), track, domain.VersionPreference{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Best.SpotifyID != "qingtian" || result.Best.Strategy != domain.MatchByAlias {
+		t.Errorf("best = %s (%s), want qingtian by alias query", result.Best.SpotifyID, result.Best.Strategy)
+	}
+	if queries := api.Queries(); !slices.Contains(queries, `track:"Sunny Day" artist:"周杰伦"`) {
+		t.Errorf("title alias query not issued: %q", queries)
+	}
+}
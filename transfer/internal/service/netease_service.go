@@ -5,8 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
+	"time"
 	"transfer/internal/domain"
+	"transfer/internal/normalize"
 )
 
 type NeteaseService interface {
@@ -15,6 +19,17 @@ type NeteaseService interface {
 
 const (
 	TargetPattern = "https://music.163.com/api/v6/playlist/detail?id=%d"
+	SongDetailURL = "https://music.163.com/api/v3/song/detail"
+
+	NeteasePlaylistURLPattern = "https://music.163.com/playlist?id=%s"
+
+	// 歌曲详情按批拉取，单次请求的 ID 数量和并发请求数都有上限
+	songDetailBatchSize = 200
+	songDetailWorkers   = 4
+
+	// 单批歌曲详情失败时的重试次数和初始退避时间，退避时间每次翻倍
+	songDetailRetries = 3
+	songDetailBackoff = time.Second
 )
 
 type neteaseService struct {
@@ -54,7 +69,145 @@ func (n *neteaseService) GetPlaylist(ctx context.Context, nid int64) (*domain.Mu
 		return nil, fmt.Errorf("API returned error code: %d", apiResp.Code)
 	}
 
-	return n.convertToMusicList(&apiResp), nil
+	// 匿名请求时 tracks 只返回一部分，trackIds 才是完整列表
+	var unavailable []uint
+	if len(apiResp.Playlist.TrackIds) > len(apiResp.Playlist.Tracks) {
+		tracks, missing, err := n.fetchAllTracks(ctx, apiResp.Playlist.TrackIds, apiResp.Playlist.Tracks)
+		if err != nil {
+			return nil, err
+		}
+		apiResp.Playlist.Tracks = tracks
+		unavailable = missing
+	}
+
+	list := n.convertToMusicList(&apiResp)
+	for _, id := range unavailable {
+		list.UnavailableIDs = append(list.UnavailableIDs, fmt.Sprintf("%d", id))
+	}
+	return list, nil
+}
+
+// fetchAllTracks 按 trackIds 的顺序补全歌单中的所有歌曲
+// 已经在 tracks 中返回的歌曲直接复用，其余的分批并发拉取
+// 网易云不再返回详情的歌曲（下架或失去版权）不算失败，按原顺序放入 missing
+func (n *neteaseService) fetchAllTracks(ctx context.Context, trackIds []trackId, known []*track) (tracks []*track, missing []uint, err error) {
+	byID := make(map[uint]*track, len(trackIds))
+	for _, t := range known {
+		byID[t.Id] = t
+	}
+
+	unknown := make([]uint, 0, len(trackIds))
+	for _, tid := range trackIds {
+		if _, ok := byID[tid.Id]; !ok {
+			unknown = append(unknown, tid.Id)
+		}
+	}
+
+	var batches [][]uint
+	for i := 0; i < len(unknown); i += songDetailBatchSize {
+		end := i + songDetailBatchSize
+		if end > len(unknown) {
+			end = len(unknown)
+		}
+		batches = append(batches, unknown[i:end])
+	}
+
+	results := make([][]*track, len(batches))
+	errs := make([]error, len(batches))
+	sem := make(chan struct{}, songDetailWorkers)
+	var wg sync.WaitGroup
+
+	for i, batch := range batches {
+		wg.Add(1)
+		go func(i int, batch []uint) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i], errs[i] = n.fetchSongDetailsWithRetry(ctx, batch)
+		}(i, batch)
+	}
+	wg.Wait()
+
+	for i := range batches {
+		if errs[i] != nil {
+			return nil, nil, errs[i]
+		}
+		for _, t := range results[i] {
+			byID[t.Id] = t
+		}
+	}
+
+	// 按 trackIds 原始顺序组装
+	tracks = make([]*track, 0, len(trackIds))
+	for _, tid := range trackIds {
+		t, ok := byID[tid.Id]
+		if !ok {
+			missing = append(missing, tid.Id)
+			continue
+		}
+		tracks = append(tracks, t)
+	}
+
+	return tracks, missing, nil
+}
+
+// fetchSongDetailsWithRetry 拉取一批歌曲详情，网络错误、非 200 响应等失败时退避重试
+func (n *neteaseService) fetchSongDetailsWithRetry(ctx context.Context, ids []uint) ([]*track, error) {
+	backoff := songDetailBackoff
+	for attempt := 0; ; attempt++ {
+		songs, err := n.fetchSongDetails(ctx, ids)
+		if err == nil || attempt >= songDetailRetries || ctx.Err() != nil {
+			return songs, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// fetchSongDetails 拉取一批歌曲的详细信息
+func (n *neteaseService) fetchSongDetails(ctx context.Context, ids []uint) ([]*track, error) {
+	refs := make([]map[string]uint, 0, len(ids))
+	for _, id := range ids {
+		refs = append(refs, map[string]uint{"id": id})
+	}
+	c, err := json.Marshal(refs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode song IDs: %w", err)
+	}
+
+	form := url.Values{"c": {string(c)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, SongDetailURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch song details: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("song detail request returned status: %d", resp.StatusCode)
+	}
+
+	var apiResp SongDetailResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode song details: %w", err)
+	}
+
+	if apiResp.Code != 200 {
+		return nil, fmt.Errorf("song detail API returned error code: %d", apiResp.Code)
+	}
+
+	return apiResp.Songs, nil
 }
 
 // convertToMusicList 将 API 响应转换为领域对象
@@ -65,19 +218,30 @@ func (n *neteaseService) convertToMusicList(resp *PlaylistResponse) *domain.Musi
 	for _, track := range resp.Playlist.Tracks {
 		// 构建艺术家名称
 		artists := make([]string, 0, len(track.Ar))
+		artistAliases := make([]domain.Artist, 0, len(track.Ar))
 		for _, artist := range track.Ar {
 			if artist.Name != "" {
 				artists = append(artists, artist.Name)
+				artistAliases = append(artistAliases, domain.Artist{
+					Name:    artist.Name,
+					Aliases: aliases(artist.Name, artist.Tns, artist.Alias),
+				})
 			}
 		}
 
 		artistName := strings.Join(artists, ", ")
 
 		domainTrack := domain.Track{
-			Title:    track.Name,
-			Artist:   artistName,
-			Album:    track.Al.Name,
-			MatchKey: buildMatchKey(track.Name, artistName),
+			Title:           track.Name,
+			Artist:          artistName,
+			Album:           track.Al.Name,
+			Aliases:         aliases(track.Name, track.Tns, track.Alia),
+			Artists:         artistAliases,
+			AlbumAliases:    aliases(track.Al.Name, track.Al.Tns),
+			SourceID:        fmt.Sprintf("%d", track.Id),
+			DurationMs:      track.Dt,
+			FeaturedArtists: normalize.ParseTitle(track.Name).Featured,
+			MatchKey:        buildMatchKey(track.Name, artistName),
 		}
 
 		tracks = append(tracks, domainTrack)
@@ -90,12 +254,31 @@ func (n *neteaseService) convertToMusicList(resp *PlaylistResponse) *domain.Musi
 	}
 }
 
+// aliases 合并译名和别名，去掉空白、重复以及与原名相同的项
+// 译名排在前面，别名里常有 "电影《xxx》插曲" 这类说明，不如译名可靠
+func aliases(name string, lists ...[]string) []string {
+	seen := map[string]bool{normalize.Key(name): true}
+	var result []string
+	for _, list := range lists {
+		for _, alias := range list {
+			alias = strings.TrimSpace(alias)
+			key := normalize.Key(alias)
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			result = append(result, alias)
+		}
+	}
+	return result
+}
+
 // buildMatchKey 构建用于匹配的键
+// 去掉 "(Live)" 之类的注释，统一全半角、繁简、标点和大小写
 func buildMatchKey(title, artist string) string {
-	// 简单的标准化：去除空格，转小写
-	key := strings.ToLower(strings.TrimSpace(title))
+	key := normalize.Key(normalize.ParseTitle(title).Name)
 	if artist != "" {
-		key += "|" + strings.ToLower(strings.TrimSpace(artist))
+		key += "|" + normalize.Key(artist)
 	}
 	return key
 }
@@ -104,21 +287,37 @@ type PlaylistResponse struct {
 	Code int `json:"code"`
 
 	Playlist struct {
-		Id         int64    `json:"id"`
-		Name       string   `json:"name"`
-		Tracks     []*track `json:"tracks"`
-		TrackCount int      `json:"trackCount"`
+		Id         int64     `json:"id"`
+		Name       string    `json:"name"`
+		Tracks     []*track  `json:"tracks"`
+		TrackIds   []trackId `json:"trackIds"`
+		TrackCount int       `json:"trackCount"`
 	} `json:"playlist"`
 }
 
+type SongDetailResponse struct {
+	Code  int      `json:"code"`
+	Songs []*track `json:"songs"`
+}
+
+type trackId struct {
+	Id uint `json:"id"`
+}
+
 type track struct {
-	Id   uint   `json:"id"`
-	Name string `json:"name"`
+	Id   uint     `json:"id"`
+	Name string   `json:"name"`
+	Dt   int      `json:"dt"`   // 时长，毫秒
+	Alia []string `json:"alia"` // 别名
+	Tns  []string `json:"tns"`  // 译名
 	Ar   []struct {
-		Id   int64  `json:"id"`
-		Name string `json:"name"`
+		Id    int64    `json:"id"`
+		Name  string   `json:"name"`
+		Alias []string `json:"alias"`
+		Tns   []string `json:"tns"`
 	} `json:"ar"`
 	Al struct {
-		Name string `json:"name"`
+		Name string   `json:"name"`
+		Tns  []string `json:"tns"`
 	} `json:"al"`
 }
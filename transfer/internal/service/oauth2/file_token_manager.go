@@ -0,0 +1,195 @@
+package oauth2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileTokenManager 把所有用户的 token 保存在一个 JSON 文件中
+// refresh token 加密后落盘，每次写入都是完整替换
+type FileTokenManager struct {
+	path   string
+	cipher *tokenCipher
+	tokens map[string]*UserToken
+	mutex  sync.RWMutex
+	users  userLocks
+}
+
+// fileToken 落盘格式，refresh token 为密文
+type fileToken struct {
+	UserID       string    `json:"user_id"`
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token_enc"`
+	TokenType    string    `json:"token_type"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	Scopes       []string  `json:"scopes"`
+}
+
+func NewFileTokenManager(path string, cipher *tokenCipher) (TokenManager, error) {
+	m := &FileTokenManager{
+		path:   path,
+		cipher: cipher,
+		tokens: make(map[string]*UserToken),
+	}
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *FileTokenManager) load() error {
+	data, err := os.ReadFile(m.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read token file: %w", err)
+	}
+
+	var stored map[string]fileToken
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("failed to decode token file: %w", err)
+	}
+
+	for userID, t := range stored {
+		refreshToken, err := m.cipher.open(userID, t.RefreshToken)
+		if err != nil {
+			return fmt.Errorf("failed to load token for user %s: %w", userID, err)
+		}
+		m.tokens[userID] = &UserToken{
+			UserID:       t.UserID,
+			AccessToken:  t.AccessToken,
+			RefreshToken: refreshToken,
+			TokenType:    t.TokenType,
+			ExpiresAt:    t.ExpiresAt,
+			Scopes:       t.Scopes,
+		}
+	}
+	return nil
+}
+
+// persist 写入临时文件后 rename，调用方需持有写锁
+func (m *FileTokenManager) persist() error {
+	stored := make(map[string]fileToken, len(m.tokens))
+	for userID, t := range m.tokens {
+		refreshToken, err := m.cipher.seal(userID, t.RefreshToken)
+		if err != nil {
+			return err
+		}
+		stored[userID] = fileToken{
+			UserID:       t.UserID,
+			AccessToken:  t.AccessToken,
+			RefreshToken: refreshToken,
+			TokenType:    t.TokenType,
+			ExpiresAt:    t.ExpiresAt,
+			Scopes:       t.Scopes,
+		}
+	}
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("failed to encode tokens: %w", err)
+	}
+
+	dir := filepath.Dir(m.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create token directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(m.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write tokens: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write tokens: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), m.path); err != nil {
+		return fmt.Errorf("failed to save tokens: %w", err)
+	}
+	return nil
+}
+
+func (m *FileTokenManager) StoreUserToken(userID string, token *UserToken) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	previous, existed := m.tokens[userID]
+	m.tokens[userID] = token
+	if err := m.persist(); err != nil {
+		// 落盘失败时回滚内存状态，保持两边一致
+		if existed {
+			m.tokens[userID] = previous
+		} else {
+			delete(m.tokens, userID)
+		}
+		return err
+	}
+	return nil
+}
+
+func (m *FileTokenManager) GetUserToken(userID string) (*UserToken, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	token, exists := m.tokens[userID]
+	if !exists {
+		return nil, fmt.Errorf("token not found for user %s", userID)
+	}
+	return token, nil
+}
+
+func (m *FileTokenManager) DeleteUserToken(userID string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	previous, existed := m.tokens[userID]
+	if !existed {
+		return nil
+	}
+	delete(m.tokens, userID)
+	if err := m.persist(); err != nil {
+		m.tokens[userID] = previous
+		return err
+	}
+	return nil
+}
+
+func (m *FileTokenManager) IsTokenValid(userID string) bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	token, exists := m.tokens[userID]
+	if !exists {
+		return false
+	}
+	return time.Now().Before(token.ExpiresAt)
+}
+
+func (m *FileTokenManager) UpdateUserToken(userID string, update func(current *UserToken) (*UserToken, error)) (*UserToken, error) {
+	unlock := m.users.lock(userID)
+	defer unlock()
+
+	current, err := m.GetUserToken(userID)
+	if err != nil {
+		return nil, err
+	}
+	next, err := update(current)
+	if err != nil {
+		return nil, err
+	}
+	if next == current {
+		return current, nil
+	}
+	if err := m.StoreUserToken(userID, next); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
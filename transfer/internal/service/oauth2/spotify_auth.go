@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
+	"net/http"
 	"sync"
 	"time"
 
@@ -18,6 +19,10 @@ type SpotifyOAuthService interface {
 	HandleCallback(code, state string) (*UserToken, error)
 	RefreshUserToken(refreshToken string) (*UserToken, error)
 	GetAuthenticatedClient(userID string) (spotify.Client, error)
+	// ValidUserToken 返回未过期的 token，过期时刷新并原子地写回
+	ValidUserToken(userID string) (*UserToken, error)
+	// NewClient 用给定的 token 创建 Spotify 客户端
+	NewClient(token *UserToken) spotify.Client
 	RevokeToken(userID string) error
 }
 
@@ -27,6 +32,9 @@ type TokenManager interface {
 	GetUserToken(userID string) (*UserToken, error)
 	DeleteUserToken(userID string) error
 	IsTokenValid(userID string) bool
+	// UpdateUserToken 原子地读取、更新并保存 token
+	// update 返回 current 本身表示无需写入
+	UpdateUserToken(userID string, update func(current *UserToken) (*UserToken, error)) (*UserToken, error)
 }
 
 type UserToken struct {
@@ -48,6 +56,7 @@ type SpotifyAuth struct {
 type MemoryTokenManager struct {
 	tokens map[string]*UserToken
 	mutex  sync.RWMutex
+	users  userLocks
 }
 
 func NewMemoryTokenManager() TokenManager {
@@ -90,14 +99,34 @@ func (m *MemoryTokenManager) IsTokenValid(userID string) bool {
 	return time.Now().Before(token.ExpiresAt)
 }
 
+func (m *MemoryTokenManager) UpdateUserToken(userID string, update func(current *UserToken) (*UserToken, error)) (*UserToken, error) {
+	unlock := m.users.lock(userID)
+	defer unlock()
+
+	current, err := m.GetUserToken(userID)
+	if err != nil {
+		return nil, err
+	}
+	next, err := update(current)
+	if err != nil {
+		return nil, err
+	}
+	if next != current {
+		m.StoreUserToken(userID, next)
+	}
+	return next, nil
+}
+
 // SpotifyOAuth OAuth 服务实现
 type SpotifyOAuth struct {
 	authenticator spotify.Authenticator
 	config        *oauth2.Config // 用于 refresh token 操作
 	tokenManager  TokenManager
+	apiClient     *http.Client // 调用 Spotify Web API 使用的 HTTP 客户端
 }
 
-func NewSpotifyOAuth(clientID, clientSecret, redirectURL string, tokenManager TokenManager) SpotifyOAuthService {
+// NewSpotifyOAuth 创建 OAuth 服务，transport 用于用户客户端访问 Spotify Web API
+func NewSpotifyOAuth(clientID, clientSecret, redirectURL string, tokenManager TokenManager, transport http.RoundTripper) SpotifyOAuthService {
 	auth := spotify.NewAuthenticator(redirectURL,
 		spotify.ScopeUserReadPrivate,
 		spotify.ScopePlaylistReadPrivate,
@@ -127,6 +156,7 @@ func NewSpotifyOAuth(clientID, clientSecret, redirectURL string, tokenManager To
 		authenticator: auth,
 		config:        config,
 		tokenManager:  tokenManager,
+		apiClient:     &http.Client{Transport: transport},
 	}
 }
 
@@ -174,23 +204,40 @@ func (s *SpotifyOAuth) RefreshUserToken(refreshToken string) (*UserToken, error)
 	return userToken, nil
 }
 
-func (s *SpotifyOAuth) GetAuthenticatedClient(userID string) (spotify.Client, error) {
-	token, err := s.tokenManager.GetUserToken(userID)
-	if err != nil {
-		return spotify.Client{}, err
-	}
+func (s *SpotifyOAuth) ValidUserToken(userID string) (*UserToken, error) {
+	return s.tokenManager.UpdateUserToken(userID, func(current *UserToken) (*UserToken, error) {
+		// 并发请求中先拿到锁的那个已经刷新过，后来者直接复用
+		if time.Now().Before(current.ExpiresAt) {
+			return current, nil
+		}
+		if current.RefreshToken == "" {
+			return nil, fmt.Errorf("token expired and no refresh token for user %s", userID)
+		}
 
-	// 检查 token 是否过期，如需要则刷新
-	if time.Now().After(token.ExpiresAt) {
-		newToken, err := s.RefreshUserToken(token.RefreshToken)
+		newToken, err := s.RefreshUserToken(current.RefreshToken)
 		if err != nil {
-			return spotify.Client{}, fmt.Errorf("failed to refresh expired token: %w", err)
+			return nil, fmt.Errorf("failed to refresh expired token: %w", err)
 		}
 		newToken.UserID = userID
-		s.tokenManager.StoreUserToken(userID, newToken)
-		token = newToken
+		// Spotify 刷新时不一定返回新的 refresh token
+		if newToken.RefreshToken == "" {
+			newToken.RefreshToken = current.RefreshToken
+		}
+		return newToken, nil
+	})
+}
+
+func (s *SpotifyOAuth) GetAuthenticatedClient(userID string) (spotify.Client, error) {
+	// 检查 token 是否过期，如需要则刷新
+	token, err := s.ValidUserToken(userID)
+	if err != nil {
+		return spotify.Client{}, err
 	}
 
+	return s.NewClient(token), nil
+}
+
+func (s *SpotifyOAuth) NewClient(token *UserToken) spotify.Client {
 	oauthToken := &oauth2.Token{
 		AccessToken:  token.AccessToken,
 		RefreshToken: token.RefreshToken,
@@ -198,7 +245,8 @@ func (s *SpotifyOAuth) GetAuthenticatedClient(userID string) (spotify.Client, er
 		Expiry:       token.ExpiresAt,
 	}
 
-	return spotify.Authenticator{}.NewClient(oauthToken), nil
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, s.apiClient)
+	return spotify.NewClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(oauthToken)))
 }
 
 func (s *SpotifyOAuth) RevokeToken(userID string) error {
@@ -212,18 +260,39 @@ func (s *SpotifyOAuth) RevokeToken(userID string) error {
 // 	TokenURL     string
 // }
 
-func NewSpotifyAuth(clientID, clientSecret string) spotify.Client {
+const (
+	// appTokenExpiryDelta 应用级 token 提前多久续期，避免请求途中过期
+	appTokenExpiryDelta = time.Minute
+	appTokenTimeout     = 10 * time.Second
+)
+
+// NewSpotifyAuth 创建应用级别（client credentials）的客户端
+// token 在第一次请求时才获取，过期前自动续期；
+// 获取失败只影响当次请求，下次请求会重新获取，启动时 token 端点不可用也不会让进程退出
+func NewSpotifyAuth(clientID, clientSecret string, transport http.RoundTripper) spotify.Client {
 	authConfig := &clientcredentials.Config{
 		ClientID:     clientID,
 		ClientSecret: clientSecret,
 		TokenURL:     spotify.TokenURL,
 	}
-	accessToken, err := authConfig.Token(context.Background())
+
+	tokenSource := oauth2.ReuseTokenSourceWithExpiry(nil, &clientCredentialsSource{config: authConfig}, appTokenExpiryDelta)
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: transport})
+	return spotify.NewClient(oauth2.NewClient(ctx, tokenSource))
+}
+
+// clientCredentialsSource 每次调用都向 token 端点请求新 token，缓存和续期交给 ReuseTokenSource
+type clientCredentialsSource struct {
+	config *clientcredentials.Config
+}
+
+func (c *clientCredentialsSource) Token() (*oauth2.Token, error) {
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Timeout: appTokenTimeout})
+	token, err := c.config.Token(ctx)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("failed to fetch app token: %w", err)
 	}
-
-	return spotify.Authenticator{}.NewClient(accessToken)
+	return token, nil
 }
 
 // GenerateSecureState 生成安全的 state 参数（添加这个函数）
@@ -0,0 +1,148 @@
+package oauth2
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const createTokenTable = `
+CREATE TABLE IF NOT EXISTS user_tokens (
+	user_id           TEXT PRIMARY KEY,
+	access_token      TEXT NOT NULL,
+	refresh_token_enc TEXT NOT NULL,
+	token_type        TEXT NOT NULL,
+	expires_at        INTEGER NOT NULL,
+	scopes            TEXT NOT NULL
+)`
+
+// SQLiteTokenManager 把 token 保存在 SQLite 中，refresh token 加密存储
+type SQLiteTokenManager struct {
+	db     *sql.DB
+	cipher *tokenCipher
+	users  userLocks
+}
+
+func NewSQLiteTokenManager(path string, cipher *tokenCipher) (TokenManager, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open token database: %w", err)
+	}
+	if _, err := db.Exec(createTokenTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create token table: %w", err)
+	}
+	return &SQLiteTokenManager{
+		db:     db,
+		cipher: cipher,
+	}, nil
+}
+
+func (m *SQLiteTokenManager) StoreUserToken(userID string, token *UserToken) error {
+	refreshToken, err := m.cipher.seal(userID, token.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.db.Exec(`
+		INSERT INTO user_tokens (user_id, access_token, refresh_token_enc, token_type, expires_at, scopes)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			access_token = excluded.access_token,
+			refresh_token_enc = excluded.refresh_token_enc,
+			token_type = excluded.token_type,
+			expires_at = excluded.expires_at,
+			scopes = excluded.scopes`,
+		userID, token.AccessToken, refreshToken, token.TokenType, token.ExpiresAt.UnixMilli(), strings.Join(token.Scopes, " "))
+	if err != nil {
+		return fmt.Errorf("failed to store token for user %s: %w", userID, err)
+	}
+	return nil
+}
+
+func (m *SQLiteTokenManager) GetUserToken(userID string) (*UserToken, error) {
+	var (
+		token        UserToken
+		refreshToken string
+		expiresAt    int64
+		scopes       string
+	)
+	err := m.db.QueryRow(`
+		SELECT access_token, refresh_token_enc, token_type, expires_at, scopes
+		FROM user_tokens WHERE user_id = ?`, userID).
+		Scan(&token.AccessToken, &refreshToken, &token.TokenType, &expiresAt, &scopes)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("token not found for user %s", userID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token for user %s: %w", userID, err)
+	}
+
+	token.RefreshToken, err = m.cipher.open(userID, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	token.UserID = userID
+	token.ExpiresAt = time.UnixMilli(expiresAt)
+	token.Scopes = strings.Fields(scopes)
+	return &token, nil
+}
+
+func (m *SQLiteTokenManager) DeleteUserToken(userID string) error {
+	if _, err := m.db.Exec(`DELETE FROM user_tokens WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to delete token for user %s: %w", userID, err)
+	}
+	return nil
+}
+
+func (m *SQLiteTokenManager) IsTokenValid(userID string) bool {
+	var expiresAt int64
+	err := m.db.QueryRow(`SELECT expires_at FROM user_tokens WHERE user_id = ?`, userID).Scan(&expiresAt)
+	if err != nil {
+		return false
+	}
+	return time.Now().Before(time.UnixMilli(expiresAt))
+}
+
+// UpdateUserToken 进程内按用户串行；写入时以旧 access token 做条件更新，
+// 多个进程共用同一个数据库时，后完成的刷新不会覆盖先写入的结果
+func (m *SQLiteTokenManager) UpdateUserToken(userID string, update func(current *UserToken) (*UserToken, error)) (*UserToken, error) {
+	unlock := m.users.lock(userID)
+	defer unlock()
+
+	current, err := m.GetUserToken(userID)
+	if err != nil {
+		return nil, err
+	}
+	next, err := update(current)
+	if err != nil {
+		return nil, err
+	}
+	if next == current {
+		return current, nil
+	}
+
+	refreshToken, err := m.cipher.seal(userID, next.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	res, err := m.db.Exec(`
+		UPDATE user_tokens
+		SET access_token = ?, refresh_token_enc = ?, token_type = ?, expires_at = ?, scopes = ?
+		WHERE user_id = ? AND access_token = ?`,
+		next.AccessToken, refreshToken, next.TokenType, next.ExpiresAt.UnixMilli(), strings.Join(next.Scopes, " "),
+		userID, current.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update token for user %s: %w", userID, err)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		// 其他进程已经写入了更新的 token，以数据库中的为准
+		return m.GetUserToken(userID)
+	}
+	return next, nil
+}
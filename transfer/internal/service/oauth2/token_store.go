@@ -0,0 +1,121 @@
+package oauth2
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	TokenStoreMemory = "memory"
+	TokenStoreFile   = "file"
+	TokenStoreSQLite = "sqlite"
+)
+
+// TokenStoreConfig 选择 Token 存储后端
+type TokenStoreConfig struct {
+	Backend string // memory、file 或 sqlite
+	Path    string // file 后端的 JSON 文件路径，sqlite 后端的数据库文件路径
+	// EncryptionKey base64 编码的 32 字节密钥，用于加密落盘的 refresh token
+	EncryptionKey string
+}
+
+// NewTokenManager 按配置创建 Token 管理器
+func NewTokenManager(config TokenStoreConfig) (TokenManager, error) {
+	if config.Backend == "" || config.Backend == TokenStoreMemory {
+		return NewMemoryTokenManager(), nil
+	}
+
+	if config.Path == "" {
+		return nil, fmt.Errorf("token store %s requires a path", config.Backend)
+	}
+	sealer, err := newTokenCipher(config.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.Backend {
+	case TokenStoreFile:
+		return NewFileTokenManager(config.Path, sealer)
+	case TokenStoreSQLite:
+		return NewSQLiteTokenManager(config.Path, sealer)
+	default:
+		return nil, fmt.Errorf("unknown token store backend: %s", config.Backend)
+	}
+}
+
+// tokenCipher 使用 AES-GCM 加密 refresh token
+type tokenCipher struct {
+	aead cipher.AEAD
+}
+
+func newTokenCipher(encodedKey string) (*tokenCipher, error) {
+	if encodedKey == "" {
+		return nil, errors.New("token encryption key is required for persistent token stores")
+	}
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("token encryption key must be base64: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("token encryption key must be 32 bytes, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return &tokenCipher{aead: aead}, nil
+}
+
+// seal 加密并编码为 base64(nonce|ciphertext)，userID 作为附加数据防止密文被挪用
+func (c *tokenCipher) seal(userID, plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), []byte(userID))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *tokenCipher) open(userID, encoded string) (string, error) {
+	if encoded == "" {
+		return "", nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode refresh token: %w", err)
+	}
+	size := c.aead.NonceSize()
+	if len(sealed) < size {
+		return "", errors.New("refresh token ciphertext too short")
+	}
+	plaintext, err := c.aead.Open(nil, sealed[:size], sealed[size:], []byte(userID))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt refresh token: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// userLocks 按用户加锁，保证同一用户的 token 更新串行执行
+type userLocks struct {
+	locks sync.Map
+}
+
+func (u *userLocks) lock(userID string) func() {
+	m, _ := u.locks.LoadOrStore(userID, &sync.Mutex{})
+	mutex := m.(*sync.Mutex)
+	mutex.Lock()
+	return mutex.Unlock
+}
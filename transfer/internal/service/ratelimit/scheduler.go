@@ -0,0 +1,284 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRequestsPerSecond = 5
+	DefaultBurst             = 10
+	DefaultMaxRetries        = 5
+	DefaultBaseBackoff       = 500 * time.Millisecond
+	DefaultMaxBackoff        = 30 * time.Second
+
+	// 429 没有带 Retry-After 时的暂停时间
+	defaultRetryAfter = time.Second
+)
+
+// Config 请求预算和重试策略，预算由所有用户共享
+type Config struct {
+	RequestsPerSecond float64
+	Burst             int
+	MaxRetries        int
+	BaseBackoff       time.Duration
+	MaxBackoff        time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		RequestsPerSecond: DefaultRequestsPerSecond,
+		Burst:             DefaultBurst,
+		MaxRetries:        DefaultMaxRetries,
+		BaseBackoff:       DefaultBaseBackoff,
+		MaxBackoff:        DefaultMaxBackoff,
+	}
+}
+
+// StatusError Spotify 返回了可重试的状态码（429，或幂等请求的 5xx）
+type StatusError struct {
+	Status     int
+	RetryAfter time.Duration // 仅 429 时有值
+}
+
+func (e *StatusError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("spotify: HTTP %d, retry after %s", e.Status, e.RetryAfter)
+	}
+	return fmt.Sprintf("spotify: HTTP %d", e.Status)
+}
+
+// Scheduler 所有 Spotify 调用共用的调度器
+// 用令牌桶限制请求速率；收到 429 时按 Retry-After 暂停所有调用
+type Scheduler struct {
+	config Config
+
+	mutex       sync.Mutex
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+func New(config Config) *Scheduler {
+	defaults := DefaultConfig()
+	if config.RequestsPerSecond <= 0 {
+		config.RequestsPerSecond = defaults.RequestsPerSecond
+	}
+	if config.Burst <= 0 {
+		config.Burst = defaults.Burst
+	}
+	if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	}
+	if config.BaseBackoff <= 0 {
+		config.BaseBackoff = defaults.BaseBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaults.MaxBackoff
+	}
+
+	return &Scheduler{
+		config: config,
+		tokens: float64(config.Burst),
+		last:   time.Now(),
+	}
+}
+
+// Do 等到预算允许后执行 fn，遇到可重试的错误时按 Retry-After 或带抖动的指数退避重试
+func (s *Scheduler) Do(ctx context.Context, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		if err := s.sleep(ctx, s.reserve()); err != nil {
+			return err
+		}
+
+		err := fn()
+
+		var statusErr *StatusError
+		if err == nil || !errors.As(err, &statusErr) || attempt >= s.config.MaxRetries {
+			return err
+		}
+
+		// 429 已经在 Transport 中设置了全局暂停，下一轮 reserve 会等待
+		if statusErr.Status != http.StatusTooManyRequests {
+			if err := s.sleep(ctx, s.backoff(attempt)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// reserve 预订一个令牌，返回需要等待的时间
+func (s *Scheduler) reserve() time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	s.tokens = min(float64(s.config.Burst), s.tokens+now.Sub(s.last).Seconds()*s.config.RequestsPerSecond)
+	s.last = now
+	s.tokens--
+
+	var delay time.Duration
+	if s.tokens < 0 {
+		delay = time.Duration(-s.tokens / s.config.RequestsPerSecond * float64(time.Second))
+	}
+	if pause := s.pausedUntil.Sub(now); pause > delay {
+		delay = pause
+	}
+	return delay
+}
+
+// backoff 指数退避，取 [d/2, d] 之间的随机值避免多个调用同时重试
+func (s *Scheduler) backoff(attempt int) time.Duration {
+	d := s.config.BaseBackoff << attempt
+	if d <= 0 || d > s.config.MaxBackoff {
+		d = s.config.MaxBackoff
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+func (s *Scheduler) pause(d time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if until := time.Now().Add(d); until.After(s.pausedUntil) {
+		s.pausedUntil = until
+	}
+}
+
+func (s *Scheduler) pauseRemaining() time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return time.Until(s.pausedUntil)
+}
+
+// sleep 等待 d，同时计入 ctx 中的等待统计
+func (s *Scheduler) sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	if tracker := trackerFrom(ctx); tracker != nil {
+		tracker.begin()
+		defer tracker.end()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Transport 包装 Spotify 客户端使用的 RoundTripper
+// 全局暂停期间的请求会先等待；可重试的响应转换为 StatusError 交给 Do 处理
+func (s *Scheduler) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{scheduler: s, base: base}
+}
+
+type transport struct {
+	scheduler *Scheduler
+	base      http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.scheduler.sleep(req.Context(), t.scheduler.pauseRemaining()); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	// 429 表示请求没有被处理，任何方法都可以重试；
+	// 5xx 时 POST 可能已经生效（例如已经加入歌单），只重试幂等的方法
+	retryable := resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode >= http.StatusInternalServerError && req.Method != http.MethodPost)
+	if !retryable {
+		return resp, nil
+	}
+
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	statusErr := &StatusError{Status: resp.StatusCode}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		statusErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+		t.scheduler.pause(statusErr.RetryAfter)
+	}
+	return nil, statusErr
+}
+
+func parseRetryAfter(raw string) time.Duration {
+	if seconds, err := strconv.Atoi(raw); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(raw); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return defaultRetryAfter
+}
+
+// WaitTracker 统计一次迁移因限流实际耽误的时间
+// 多个并发的调用同时等待时只算一次：记录至少有一个调用在等待的时长，而不是各自等待时间之和
+type WaitTracker struct {
+	mutex   sync.Mutex
+	waiting int       // 正在等待的调用数量
+	since   time.Time // 本段等待开始的时间
+	total   time.Duration
+}
+
+func (w *WaitTracker) begin() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.waiting == 0 {
+		w.since = time.Now()
+	}
+	w.waiting++
+}
+
+func (w *WaitTracker) end() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.waiting--
+	if w.waiting == 0 {
+		w.total += time.Since(w.since)
+	}
+}
+
+// Total 到目前为止的等待时间，包括仍在进行的等待
+func (w *WaitTracker) Total() time.Duration {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.waiting > 0 {
+		return w.total + time.Since(w.since)
+	}
+	return w.total
+}
+
+type trackerKey struct{}
+
+// TrackWaits 返回携带等待统计的 ctx，经由该 ctx 调用 Do 的等待都会计入
+func TrackWaits(ctx context.Context) (context.Context, *WaitTracker) {
+	tracker := &WaitTracker{}
+	return context.WithValue(ctx, trackerKey{}, tracker), tracker
+}
+
+func trackerFrom(ctx context.Context) *WaitTracker {
+	tracker, _ := ctx.Value(trackerKey{}).(*WaitTracker)
+	return tracker
+}
@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"transfer/internal/spotifytest"
+
+	"github.com/zmb3/spotify"
+)
+
+// 并发的调用同时等待时，等待时间按实际耽误的时长计算，不累加
+func TestWaitTrackerCountsOverlappingWaitsOnce(t *testing.T) {
+	s := New(DefaultConfig())
+	ctx, tracker := TrackWaits(context.Background())
+
+	const wait = 50 * time.Millisecond
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.sleep(ctx, wait)
+		}()
+	}
+	wg.Wait()
+
+	if total := tracker.Total(); total < wait || total >= 2*wait {
+		t.Errorf("total wait = %s, want about %s", total, wait)
+	}
+}
+
+// 收到 429 时按 Retry-After 暂停后重试，暂停时间计入本次迁移的等待统计
+func TestDoRetriesAfter429(t *testing.T) {
+	api := spotifytest.New()
+	api.AddTrack(spotifytest.Track("track", "Song", "Band", 200000))
+	var requests atomic.Int32
+	api.Intercept = func(req *http.Request) *http.Response {
+		if !strings.HasSuffix(req.URL.Path, "/tracks/track") {
+			return nil
+		}
+		if requests.Add(1) == 1 {
+			return spotifytest.Respond(http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}})
+		}
+		return nil
+	}
+
+	s := New(Config{RequestsPerSecond: 1000, Burst: 1000, MaxRetries: 2})
+	client := spotify.NewClient(&http.Client{Transport: s.Transport(api)})
+	ctx, tracker := TrackWaits(context.Background())
+
+	start := time.Now()
+	var track *spotify.FullTrack
+	err := s.Do(ctx, func() (err error) {
+		track, err = client.GetTrack("track")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if track.ID != "track" || requests.Load() != 2 {
+		t.Errorf("got track %q after %d requests, want track after 2", track.ID, requests.Load())
+	}
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("retried after %s, want to wait for Retry-After", elapsed)
+	}
+	if total := tracker.Total(); total < 900*time.Millisecond {
+		t.Errorf("recorded wait = %s, want about 1s", total)
+	}
+}
+
+// 重试次数用完后返回 StatusError
+func TestDoGivesUpAfterMaxRetries(t *testing.T) {
+	api := spotifytest.New()
+	var requests atomic.Int32
+	api.Intercept = func(req *http.Request) *http.Response {
+		requests.Add(1)
+		return spotifytest.Respond(http.StatusServiceUnavailable, nil)
+	}
+
+	s := New(Config{RequestsPerSecond: 1000, Burst: 1000, MaxRetries: 2, BaseBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+	client := spotify.NewClient(&http.Client{Transport: s.Transport(api)})
+
+	err := s.Do(context.Background(), func() error {
+		_, err := client.GetTrack("track")
+		return err
+	})
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Status != http.StatusServiceUnavailable {
+		t.Fatalf("err = %v, want StatusError 503", err)
+	}
+	if requests.Load() != 3 {
+		t.Errorf("sent %d requests, want 3", requests.Load())
+	}
+}
@@ -0,0 +1,82 @@
+package review
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"transfer/internal/domain"
+)
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusResolving Status = "resolving" // 正在把选中的歌曲加入歌单，其他请求不能再处理
+	StatusApproved  Status = "approved"
+	StatusSkipped   Status = "skipped"
+)
+
+var (
+	ErrItemNotFound    = errors.New("review item not found")
+	ErrAlreadyResolved = errors.New("review item already resolved")
+	ErrResolving       = errors.New("review item is being resolved")
+	ErrInvalidChoice   = errors.New("spotify track is not a candidate of this item")
+	ErrInvalidURL      = errors.New("invalid spotify track URL")
+)
+
+// Item 一首等待人工确认的歌曲，连同它的候选
+type Item struct {
+	ID         string             `json:"id"`
+	JobID      string             `json:"job_id,omitempty"` // 产生这首歌曲的迁移任务
+	Index      int                `json:"index"`            // 歌曲在源歌单中的位置，对应任务结果中的记录
+	UserID     string             `json:"user_id"`
+	PlaylistID string             `json:"playlist_id"`
+	Track      domain.Track       `json:"track"`
+	Confidence float64            `json:"confidence"`
+	Candidates []domain.Candidate `json:"candidates"`
+	Status     Status             `json:"status"`
+	ChosenID   string             `json:"chosen_id,omitempty"` // 最终添加的 Spotify track ID
+	CreatedAt  time.Time          `json:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+}
+
+func (i *Item) candidate(spotifyID string) (domain.Candidate, bool) {
+	for _, c := range i.Candidates {
+		if c.SpotifyID == spotifyID {
+			return c, true
+		}
+	}
+	return domain.Candidate{}, false
+}
+
+var spotifyIDPattern = regexp.MustCompile(`^[0-9A-Za-z]{22}$`)
+
+// ParseTrackURL 从 Spotify 链接或 URI 中解析出 track ID
+// 支持 https://open.spotify.com/track/<id>、带语言前缀的链接和 spotify:track:<id>
+func ParseTrackURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+
+	var id string
+	if rest, ok := strings.CutPrefix(raw, "spotify:track:"); ok {
+		id = rest
+	} else {
+		u, err := url.Parse(raw)
+		if err != nil || u.Host != "open.spotify.com" {
+			return "", ErrInvalidURL
+		}
+		segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+		for i := 0; i+1 < len(segments); i++ {
+			if segments[i] == "track" {
+				id = segments[i+1]
+				break
+			}
+		}
+	}
+
+	if !spotifyIDPattern.MatchString(id) {
+		return "", ErrInvalidURL
+	}
+	return id, nil
+}
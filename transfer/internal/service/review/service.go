@@ -0,0 +1,266 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+	"transfer/internal/domain"
+	"transfer/internal/service/ratelimit"
+	"transfer/internal/storage"
+
+	"github.com/zmb3/spotify"
+)
+
+// Service 管理待确认歌曲，确认后把选中的歌曲加入目标歌单
+type Service struct {
+	store     Store
+	items     map[string]*Item
+	mutex     sync.RWMutex
+	scheduler *ratelimit.Scheduler
+
+	resolved func(item Item) // 条目确认或跳过后回调，用于写回来源任务
+}
+
+// NewService 从 store 加载上次保存的条目
+// 重启时仍在处理中的条目退回待确认，由用户重新确认
+func NewService(store Store, scheduler *ratelimit.Scheduler) (*Service, error) {
+	items, err := store.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load review items: %w", err)
+	}
+
+	s := &Service{
+		store:     store,
+		items:     make(map[string]*Item, len(items)),
+		scheduler: scheduler,
+	}
+	for _, item := range items {
+		if item.Status == StatusResolving {
+			item.Status = StatusPending
+			s.save(item)
+		}
+		s.items[item.ID] = item
+	}
+	return s, nil
+}
+
+// OnResolved 设置条目确认或跳过后的回调，回调在锁外执行
+func (s *Service) OnResolved(fn func(item Item)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.resolved = fn
+}
+
+// Park 把迁移任务中置信度不足的歌曲放入确认队列
+func (s *Service) Park(jobID, userID, playlistID string, tracks []domain.ReviewTrack) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	for _, t := range tracks {
+		id, err := storage.NewID()
+		if err != nil {
+			return err
+		}
+		item := &Item{
+			ID:         id,
+			JobID:      jobID,
+			Index:      t.Index,
+			UserID:     userID,
+			PlaylistID: playlistID,
+			Track:      t.Track,
+			Confidence: t.Confidence,
+			Candidates: t.Candidates,
+			Status:     StatusPending,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		}
+		if err := s.store.Save(item); err != nil {
+			return err
+		}
+		s.items[id] = item
+	}
+	return nil
+}
+
+// List 返回用户待确认和正在处理的歌曲，按加入顺序排列
+func (s *Service) List(userID string) []*Item {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := make([]*Item, 0)
+	for _, item := range s.items {
+		if item.UserID == userID && (item.Status == StatusPending || item.Status == StatusResolving) {
+			copied := *item
+			result = append(result, &copied)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// Resolved 返回来自该任务、已经确认或跳过的条目，按处理顺序排列
+func (s *Service) Resolved(jobID string) []Item {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := make([]Item, 0)
+	for _, item := range s.items {
+		if item.JobID == jobID && (item.Status == StatusApproved || item.Status == StatusSkipped) {
+			result = append(result, *item)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].UpdatedAt.Before(result[j].UpdatedAt)
+	})
+	return result
+}
+
+// Approve 接受匹配度最高的候选
+func (s *Service) Approve(ctx context.Context, client spotify.Client, userID, itemID string) (*Item, error) {
+	return s.resolve(ctx, client, userID, itemID, func(item *Item) (string, error) {
+		if len(item.Candidates) == 0 {
+			return "", ErrInvalidChoice
+		}
+		return item.Candidates[0].SpotifyID, nil
+	})
+}
+
+// Pick 从候选中选择另一首
+func (s *Service) Pick(ctx context.Context, client spotify.Client, userID, itemID, spotifyID string) (*Item, error) {
+	return s.resolve(ctx, client, userID, itemID, func(item *Item) (string, error) {
+		if _, ok := item.candidate(spotifyID); !ok {
+			return "", ErrInvalidChoice
+		}
+		return spotifyID, nil
+	})
+}
+
+// PickURL 使用用户粘贴的 Spotify 链接，先确认歌曲存在
+func (s *Service) PickURL(ctx context.Context, client spotify.Client, userID, itemID, rawURL string) (*Item, error) {
+	spotifyID, err := ParseTrackURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.resolve(ctx, client, userID, itemID, func(item *Item) (string, error) {
+		err := s.scheduler.Do(ctx, func() error {
+			_, err := client.GetTrack(spotify.ID(spotifyID))
+			return err
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to get spotify track %s: %w", spotifyID, err)
+		}
+		return spotifyID, nil
+	})
+}
+
+// Skip 放弃这首歌曲，不添加任何内容
+func (s *Service) Skip(userID, itemID string) (*Item, error) {
+	s.mutex.Lock()
+	item, err := s.pending(userID, itemID)
+	if err != nil {
+		s.mutex.Unlock()
+		return nil, err
+	}
+
+	item.Status = StatusSkipped
+	item.UpdatedAt = time.Now()
+	s.save(item)
+	copied, resolved := *item, s.resolved
+	s.mutex.Unlock()
+
+	s.notify(resolved, copied)
+	return &copied, nil
+}
+
+// resolve 确定最终选择并添加到目标歌单，添加成功才标记为已确认
+// 调用 Spotify 时不持有锁：先把条目标记为处理中，结束后再加锁提交或退回待确认
+func (s *Service) resolve(ctx context.Context, client spotify.Client, userID, itemID string, choose func(item *Item) (string, error)) (*Item, error) {
+	item, err := s.claim(userID, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	spotifyID, err := choose(item)
+	if err == nil {
+		err = s.scheduler.Do(ctx, func() error {
+			_, err := client.AddTracksToPlaylist(spotify.ID(item.PlaylistID), spotify.ID(spotifyID))
+			return err
+		})
+		if err != nil {
+			err = fmt.Errorf("failed to add to playlist: %w", err)
+		}
+	}
+
+	s.mutex.Lock()
+	claimed := s.items[itemID]
+	claimed.UpdatedAt = time.Now()
+	if err != nil {
+		claimed.Status = StatusPending
+		s.save(claimed)
+		s.mutex.Unlock()
+		return nil, err
+	}
+	claimed.Status = StatusApproved
+	claimed.ChosenID = spotifyID
+	s.save(claimed)
+	copied, resolved := *claimed, s.resolved
+	s.mutex.Unlock()
+
+	s.notify(resolved, copied)
+	return &copied, nil
+}
+
+// claim 把待确认的条目标记为处理中，返回它的副本
+func (s *Service) claim(userID, itemID string) (*Item, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	item, err := s.pending(userID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	item.Status = StatusResolving
+	item.UpdatedAt = time.Now()
+	s.save(item)
+	copied := *item
+	return &copied, nil
+}
+
+// pending 查找属于该用户且尚未处理的条目，调用方需持有锁
+func (s *Service) pending(userID, itemID string) (*Item, error) {
+	item, ok := s.items[itemID]
+	if !ok || item.UserID != userID {
+		return nil, ErrItemNotFound
+	}
+	switch item.Status {
+	case StatusPending:
+		return item, nil
+	case StatusResolving:
+		return nil, ErrResolving
+	default:
+		return nil, ErrAlreadyResolved
+	}
+}
+
+// notify 把处理结果交给回调，没有来源任务的条目不回调
+func (s *Service) notify(resolved func(item Item), item Item) {
+	if resolved != nil && item.JobID != "" {
+		resolved(item)
+	}
+}
+
+// save 持久化条目，失败只记录日志，内存中的状态仍然有效
+func (s *Service) save(item *Item) {
+	if err := s.store.Save(item); err != nil {
+		log.Printf("review item %s: failed to save: %v", item.ID, err)
+	}
+}
@@ -0,0 +1,30 @@
+package review
+
+import "transfer/internal/storage"
+
+// Store 待确认歌曲的持久化接口
+type Store interface {
+	Save(item *Item) error
+	List() ([]*Item, error)
+}
+
+// FileStore 每个条目一个 JSON 文件，服务重启后确认队列仍然保留
+type FileStore struct {
+	dir *storage.Dir[Item]
+}
+
+func NewFileStore(dir string) (Store, error) {
+	d, err := storage.NewDir[Item](dir)
+	if err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: d}, nil
+}
+
+func (f *FileStore) Save(item *Item) error {
+	return f.dir.Save(item.ID, item)
+}
+
+func (f *FileStore) List() ([]*Item, error) {
+	return f.dir.List()
+}
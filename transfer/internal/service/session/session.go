@@ -1,13 +1,36 @@
 package session
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"log"
+	"net/http"
+	"strings"
 	"sync"
 	"time"
+	"transfer/internal/storage"
 
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	DefaultCookieName    = "session_id"
+	DefaultMaxAge        = 7 * 24 * time.Hour
+	DefaultIdleTimeout   = 24 * time.Hour
+	DefaultSweepInterval = 10 * time.Minute
+
+	// 同一请求内新下发的会话 ID，后续读取以它为准而不是请求里的旧 cookie
+	issuedIDKey = "session.issued_id"
+
+	// 会话保存目录中的文件名
+	snapshotID = "sessions"
+)
+
 type SessionData struct {
 	UserID      string    `json:"user_id"`
 	SpotifyID   string    `json:"spotify_id"`
@@ -23,61 +46,309 @@ type SessionManager interface {
 	DeleteSession(c *gin.Context) error
 	SetState(c *gin.Context, state string) error
 	ValidateState(c *gin.Context, state string) bool
+	// RotateSession 为当前会话换发新的 ID，登录成功后调用以防止会话固定攻击
+	RotateSession(c *gin.Context) error
+}
+
+// Config 会话 cookie 的配置
+type Config struct {
+	Secret        []byte        // HMAC 签名密钥，为空时随机生成，配置了 Dir 时生成的密钥也会保存
+	Dir           string        // 会话保存目录，为空时只保存在内存中，重启后所有用户需要重新登录
+	CookieName    string        // cookie 名称
+	MaxAge        time.Duration // 会话从创建起的最长有效期
+	IdleTimeout   time.Duration // 超过该时间没有请求则会话失效
+	SweepInterval time.Duration // 后台清理过期会话的间隔
+	Secure        bool          // 仅通过 HTTPS 发送 cookie
+	SameSite      http.SameSite
+}
+
+func DefaultConfig() Config {
+	return Config{
+		CookieName:    DefaultCookieName,
+		MaxAge:        DefaultMaxAge,
+		IdleTimeout:   DefaultIdleTimeout,
+		SweepInterval: DefaultSweepInterval,
+		// OAuth 回调是从 Spotify 跳转回来的顶级导航，Strict 会丢掉 cookie
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+type sessionEntry struct {
+	data      *SessionData
+	createdAt time.Time
+	lastSeen  time.Time
+}
+
+// snapshot 保存到文件中的全部会话和生成的签名密钥
+type snapshot struct {
+	Secret   []byte                  `json:"secret,omitempty"`
+	Sessions map[string]savedSession `json:"sessions"`
 }
 
+type savedSession struct {
+	Data      *SessionData `json:"data"`
+	CreatedAt time.Time    `json:"created_at"`
+	LastSeen  time.Time    `json:"last_seen"`
+}
+
+// MemorySessionManager 会话保存在内存中，浏览器只持有签名后的随机会话 ID
+// 配置了 Dir 时会话变化后写入文件，重启后用户保持登录；
+// 只访问不修改会话时不写文件，记录的最近访问时间最多落后一个清理间隔
 type MemorySessionManager struct {
-	sessions map[string]*SessionData
-	mutex    sync.RWMutex
+	config   Config
+	sessions map[string]*sessionEntry
+	mutex    sync.Mutex
+	store    *storage.Dir[snapshot]
+	secret   []byte // 随机生成的密钥，需要随会话一起保存
+}
+
+// NewMemorySessionManager 创建会话管理器，并在 ctx 结束前定期清理过期会话
+func NewMemorySessionManager(ctx context.Context, config Config) (SessionManager, error) {
+	defaults := DefaultConfig()
+	if config.CookieName == "" {
+		config.CookieName = defaults.CookieName
+	}
+	if config.MaxAge <= 0 {
+		config.MaxAge = defaults.MaxAge
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = defaults.IdleTimeout
+	}
+	if config.SweepInterval <= 0 {
+		config.SweepInterval = defaults.SweepInterval
+	}
+	if config.SameSite == 0 {
+		config.SameSite = defaults.SameSite
+	}
+	m := &MemorySessionManager{
+		config:   config,
+		sessions: make(map[string]*sessionEntry),
+	}
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+
+	if len(m.config.Secret) == 0 {
+		// 没有配置密钥时随机生成；不保存会话时重启后旧 cookie 全部失效
+		m.secret = make([]byte, 32)
+		if _, err := rand.Read(m.secret); err != nil {
+			return nil, fmt.Errorf("failed to generate session secret: %w", err)
+		}
+		m.config.Secret = m.secret
+		m.persist()
+	}
+	go m.sweep(ctx)
+
+	return m, nil
 }
 
-func NewMemorySessionManager() SessionManager {
-	return &MemorySessionManager{
-		sessions: make(map[string]*SessionData),
+// load 从会话目录读出上次保存的会话，没有配置 Dir 时什么也不做
+// 没有配置密钥时沿用上次生成的密钥，之前下发的 cookie 仍然有效
+func (m *MemorySessionManager) load() error {
+	if m.config.Dir == "" {
+		return nil
 	}
+
+	store, err := storage.NewDir[snapshot](m.config.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to open session directory: %w", err)
+	}
+	m.store = store
+
+	saved, err := store.Get(snapshotID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load sessions: %w", err)
+	}
+
+	if len(m.config.Secret) == 0 && len(saved.Secret) > 0 {
+		m.secret = saved.Secret
+		m.config.Secret = saved.Secret
+	}
+	now := time.Now()
+	for id, s := range saved.Sessions {
+		entry := &sessionEntry{data: s.Data, createdAt: s.CreatedAt, lastSeen: s.LastSeen}
+		if s.Data != nil && !m.expired(entry, now) {
+			m.sessions[id] = entry
+		}
+	}
+	return nil
 }
 
-func (m *MemorySessionManager) getSessionKey(c *gin.Context) string {
-	// 简单使用 client IP + User-Agent 作为 session key
-	// 生产环境应该使用更安全的方式，如 JWT 或 secure cookie
-	return fmt.Sprintf("%s_%s", c.ClientIP(), c.GetHeader("User-Agent"))
+// persist 把当前会话写入文件，调用方需持有锁；写入失败只记录日志，内存中的会话仍然有效
+func (m *MemorySessionManager) persist() {
+	if m.store == nil {
+		return
+	}
+
+	saved := &snapshot{
+		Secret:   m.secret,
+		Sessions: make(map[string]savedSession, len(m.sessions)),
+	}
+	for id, entry := range m.sessions {
+		saved.Sessions[id] = savedSession{Data: entry.data, CreatedAt: entry.createdAt, LastSeen: entry.lastSeen}
+	}
+	if err := m.store.Save(snapshotID, saved); err != nil {
+		log.Printf("failed to save sessions: %v", err)
+	}
+}
+
+// sessionID 从 cookie 中读出并校验签名，失败返回空字符串
+func (m *MemorySessionManager) sessionID(c *gin.Context) string {
+	if issued, ok := c.Get(issuedIDKey); ok {
+		return issued.(string)
+	}
+
+	value, err := c.Cookie(m.config.CookieName)
+	if err != nil {
+		return ""
+	}
+
+	id, signature, ok := strings.Cut(value, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(m.sign(id))) {
+		return ""
+	}
+	return id
+}
+
+func (m *MemorySessionManager) sign(id string) string {
+	mac := hmac.New(sha256.New, m.config.Secret)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (m *MemorySessionManager) setCookie(c *gin.Context, id string) {
+	c.Set(issuedIDKey, id)
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     m.config.CookieName,
+		Value:    id + "." + m.sign(id),
+		Path:     "/",
+		MaxAge:   int(m.config.MaxAge.Seconds()),
+		Secure:   m.config.Secure,
+		HttpOnly: true,
+		SameSite: m.config.SameSite,
+	})
+}
+
+func (m *MemorySessionManager) clearCookie(c *gin.Context) {
+	c.Set(issuedIDKey, "")
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     m.config.CookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   m.config.Secure,
+		HttpOnly: true,
+		SameSite: m.config.SameSite,
+	})
+}
+
+func (m *MemorySessionManager) expired(entry *sessionEntry, now time.Time) bool {
+	return now.Sub(entry.createdAt) > m.config.MaxAge || now.Sub(entry.lastSeen) > m.config.IdleTimeout
+}
+
+// current 返回当前请求对应的有效会话，调用方需持有写锁
+func (m *MemorySessionManager) current(c *gin.Context) (string, *sessionEntry) {
+	id := m.sessionID(c)
+	if id == "" {
+		return "", nil
+	}
+
+	entry, exists := m.sessions[id]
+	if !exists {
+		return "", nil
+	}
+
+	now := time.Now()
+	if m.expired(entry, now) {
+		delete(m.sessions, id)
+		return "", nil
+	}
+	entry.lastSeen = now
+	return id, entry
+}
+
+// create 新建会话并下发 cookie，调用方需持有写锁
+func (m *MemorySessionManager) create(c *gin.Context, data *SessionData) (*sessionEntry, error) {
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	entry := &sessionEntry{
+		data:      data,
+		createdAt: now,
+		lastSeen:  now,
+	}
+	m.sessions[id] = entry
+	m.setCookie(c, id)
+	m.persist()
+	return entry, nil
 }
 
 func (m *MemorySessionManager) SetSession(c *gin.Context, data *SessionData) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	key := m.getSessionKey(c)
-	m.sessions[key] = data
-
-	// 设置 cookie（可选，用于前端识别登录状态）
-	c.SetCookie("session_id", "authenticated", 3600, "/", "", false, true)
+	if _, entry := m.current(c); entry != nil {
+		entry.data = data
+		m.persist()
+		return nil
+	}
 
-	return nil
+	_, err := m.create(c, data)
+	return err
 }
 
 func (m *MemorySessionManager) GetSession(c *gin.Context) *SessionData {
-	m.mutex.RLock()
-	defer m.mutex.RUnlock()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 
-	key := m.getSessionKey(c)
-	session, exists := m.sessions[key]
-	if !exists {
+	_, entry := m.current(c)
+	if entry == nil {
 		return nil
 	}
 
-	return session
+	return entry.data
 }
 
 func (m *MemorySessionManager) DeleteSession(c *gin.Context) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	key := m.getSessionKey(c)
-	delete(m.sessions, key)
+	if id := m.sessionID(c); id != "" {
+		delete(m.sessions, id)
+		m.persist()
+	}
 
 	// 清除 cookie
-	c.SetCookie("session_id", "", -1, "/", "", false, true)
+	m.clearCookie(c)
+
+	return nil
+}
+
+func (m *MemorySessionManager) RotateSession(c *gin.Context) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	oldID, entry := m.current(c)
+	if entry == nil {
+		_, err := m.create(c, &SessionData{})
+		return err
+	}
 
+	id, err := newSessionID()
+	if err != nil {
+		return err
+	}
+
+	delete(m.sessions, oldID)
+	m.sessions[id] = entry
+	m.setCookie(c, id)
+	m.persist()
 	return nil
 }
 
@@ -85,33 +356,69 @@ func (m *MemorySessionManager) SetState(c *gin.Context, state string) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	key := m.getSessionKey(c)
-	session := m.sessions[key]
-	if session == nil {
-		session = &SessionData{}
-		m.sessions[key] = session
+	_, entry := m.current(c)
+	if entry == nil {
+		var err error
+		entry, err = m.create(c, &SessionData{})
+		if err != nil {
+			return err
+		}
 	}
 
-	session.State = state
-	session.StateExpiry = time.Now().Add(10 * time.Minute) // state 10分钟过期
+	entry.data.State = state
+	entry.data.StateExpiry = time.Now().Add(10 * time.Minute) // state 10分钟过期
+	m.persist()
 
 	return nil
 }
 
 func (m *MemorySessionManager) ValidateState(c *gin.Context, state string) bool {
-	m.mutex.RLock()
-	defer m.mutex.RUnlock()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 
-	key := m.getSessionKey(c)
-	session, exists := m.sessions[key]
-	if !exists {
+	_, entry := m.current(c)
+	if entry == nil {
 		return false
 	}
 
 	// 检查 state 是否匹配且未过期
-	if session.State != state || time.Now().After(session.StateExpiry) {
+	session := entry.data
+	if session.State == "" || session.State != state || time.Now().After(session.StateExpiry) {
 		return false
 	}
 
+	// state 只能使用一次
+	session.State = ""
+	m.persist()
 	return true
 }
+
+// sweep 定期删除过期会话并保存最近访问时间，直到 ctx 结束
+func (m *MemorySessionManager) sweep(ctx context.Context) {
+	ticker := time.NewTicker(m.config.SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.mutex.Lock()
+			for id, entry := range m.sessions {
+				if m.expired(entry, now) {
+					delete(m.sessions, id)
+				}
+			}
+			m.persist()
+			m.mutex.Unlock()
+		}
+	}
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session ID: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
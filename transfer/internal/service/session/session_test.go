@@ -0,0 +1,47 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestSessionSurvivesRestart 配置了 Dir 且没有配置密钥时，重启后旧 cookie 仍然有效
+func TestSessionSurvivesRestart(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first, err := NewMemorySessionManager(ctx, Config{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	if err := first.SetSession(c, &SessionData{UserID: "user", IsAuthed: true}); err != nil {
+		t.Fatal(err)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("got %d cookies, want 1", len(cookies))
+	}
+
+	second, err := NewMemorySessionManager(ctx, Config{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, _ = gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request.AddCookie(cookies[0])
+	data := second.GetSession(c)
+	if data == nil || data.UserID != "user" || !data.IsAuthed {
+		t.Fatalf("session after restart = %+v, want user %q", data, "user")
+	}
+}
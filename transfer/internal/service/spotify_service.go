@@ -2,10 +2,16 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 	"transfer/internal/domain"
+	"transfer/internal/service/ratelimit"
 
 	"github.com/zmb3/spotify"
 )
@@ -13,31 +19,134 @@ import (
 // 常量定义，消除魔数
 const (
 	SpotifyBatchLimit = 100
+	// Spotify 歌单列表接口单页上限
+	SpotifyPlaylistPageLimit = 50
+	// 每批歌曲并发搜索的默认 worker 数，实际速率仍受调度器限制
+	DefaultSearchWorkers = 4
 )
 
 var (
-	searchLimit = 1
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// 创建歌单的参数错误，由调用方修正后重试
+	ErrEmptyPlaylistName   = errors.New("source playlist name cannot be empty")
+	ErrCollaborativePublic = errors.New("collaborative playlists cannot be public")
 )
 
 type SpotifyService interface {
 	GetUserInfo(ctx context.Context, userID string) (string, error)
+	// CurrentUser 读取客户端所属用户的资料
+	CurrentUser(ctx context.Context, client spotify.Client) (*spotify.PrivateUser, error)
 	GetPlaylistsForUser(ctx context.Context, userID string) ([]*PlaylistInfo, error)
+	// ListEditablePlaylists 分页列出当前用户可以添加歌曲的歌单（自己创建的或协作歌单）
+	ListEditablePlaylists(ctx context.Context, client spotify.Client, userID, cursor string, limit int) (*PlaylistPage, error)
+	// CreatePlaylist 以源歌单为模板为用户创建一个新的 Spotify 歌单
+	CreatePlaylist(ctx context.Context, client spotify.Client, userID string, source *domain.MusicList, opts PlaylistOptions) (*PlaylistInfo, error)
 	// 重新设计：返回详细结果，不静默忽略错误
-	TransferTracksWithUserClient(ctx context.Context, client spotify.Client, playlistID string, tracks []domain.Track) (*domain.TransferResult, error)
+	TransferTracksWithUserClient(ctx context.Context, client spotify.Client, playlistID string, tracks []domain.Track, opts TransferOptions) (*domain.TransferResult, error)
+	// ContinueTransfer 在已有结果上继续迁移，只处理待处理和已匹配未添加的歌曲
+	// 出错时返回的结果仍然有效，可以保存后再次续传
+	ContinueTransfer(ctx context.Context, client spotify.Client, playlistID string, result *domain.TransferResult, opts TransferOptions) (*domain.TransferResult, error)
+	// PreviewTransfer 只为待处理的歌曲搜索匹配，不写入任何歌单
+	// 预览后的结果交给 ContinueTransfer 即可按计划添加，已匹配的歌曲不会重新搜索
+	PreviewTransfer(ctx context.Context, result *domain.TransferResult, opts TransferOptions) (*domain.TransferResult, error)
+}
+
+// TransferOptions 迁移过程的可选配置
+type TransferOptions struct {
+	// OnProgress 歌曲状态变化时调用，间隔不小于 progressInterval，每批结束时必定调用一次
+	// 参数是当前结果的快照
+	OnProgress func(result domain.TransferResult)
+	// OnEvent 每首歌曲搜索、匹配、失败或添加时调用一次
+	OnEvent func(event domain.TransferEvent)
+	// Versions 选择候选时对版本的偏好
+	Versions domain.VersionPreference
+
+	waits        *ratelimit.WaitTracker // 本次运行的限流等待统计
+	waitedBefore int64                  // 续传前已经累计的等待时间，毫秒
+	saved        *time.Time             // 上次调用 OnProgress 的时间
+}
+
+// progressInterval 两次保存进度之间的最短间隔，避免每首歌曲都写一次完整结果
+const progressInterval = 2 * time.Second
+
+// emit 补全累计数量后发出事件
+func (o TransferOptions) emit(result *domain.TransferResult, event domain.TransferEvent) {
+	o.updateWaits(result)
+	// 开始搜索不改变记录，其余事件都意味着一首歌曲有了新状态
+	if event.Type != domain.EventSearching {
+		o.checkpoint(result, false)
+	}
+	if o.OnEvent == nil {
+		return
+	}
+	event.TotalTracks = result.TotalTracks
+	event.SuccessCount = result.SuccessCount
+	event.FailedCount = len(result.FailedTracks)
+	event.RateLimitWaitMs = result.RateLimitWaitMs
+	o.OnEvent(event)
+}
+
+// checkpoint 把当前结果交给 OnProgress，距离上次不足 progressInterval 时跳过，force 时总是调用
+func (o TransferOptions) checkpoint(result *domain.TransferResult, force bool) {
+	if o.OnProgress == nil {
+		return
+	}
+	if o.saved != nil {
+		if !force && time.Since(*o.saved) < progressInterval {
+			return
+		}
+		*o.saved = time.Now()
+	}
+	o.OnProgress(snapshotResult(result))
+}
+
+func (o TransferOptions) updateWaits(result *domain.TransferResult) {
+	if o.waits != nil {
+		result.RateLimitWaitMs = o.waitedBefore + o.waits.Total().Milliseconds()
+	}
 }
 
 type PlaylistInfo struct {
-	Name string `json:"name"`
-	ID   string `json:"id"`
+	Name          string `json:"name"`
+	ID            string `json:"id"`
+	URL           string `json:"url,omitempty"`
+	TrackCount    int    `json:"track_count"`
+	OwnerID       string `json:"owner_id,omitempty"`
+	OwnerName     string `json:"owner_name,omitempty"`
+	ImageURL      string `json:"image_url,omitempty"`
+	Public        bool   `json:"public"`
+	Collaborative bool   `json:"collaborative"`
+}
+
+// PlaylistPage 一页歌单，NextCursor 为空表示没有更多
+type PlaylistPage struct {
+	Items      []*PlaylistInfo `json:"items"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// PlaylistOptions 新建歌单的可见性和描述
+type PlaylistOptions struct {
+	Public        bool
+	Collaborative bool   // Spotify 要求协作歌单必须是私有的
+	Description   string // 为空时生成指向源歌单的描述
 }
 
 type spotifyService struct {
-	client spotify.Client
+	client        spotify.Client
+	matcher       Matcher
+	scheduler     *ratelimit.Scheduler
+	searchWorkers int
 }
 
-func NewSpotifyService(client spotify.Client) SpotifyService {
+func NewSpotifyService(client spotify.Client, matcher Matcher, scheduler *ratelimit.Scheduler, searchWorkers int) SpotifyService {
+	if searchWorkers <= 0 {
+		searchWorkers = DefaultSearchWorkers
+	}
 	return &spotifyService{
-		client: client,
+		client:        client,
+		matcher:       matcher,
+		scheduler:     scheduler,
+		searchWorkers: searchWorkers,
 	}
 }
 
@@ -46,7 +155,11 @@ func (s *spotifyService) GetUserInfo(ctx context.Context, userID string) (string
 		return "", errors.New("user ID cannot be empty")
 	}
 
-	resp, err := s.client.GetUsersPublicProfile(spotify.ID(userID))
+	var resp *spotify.User
+	err := s.scheduler.Do(ctx, func() (err error) {
+		resp, err = s.client.GetUsersPublicProfile(spotify.ID(userID))
+		return err
+	})
 	if err != nil {
 		return "", fmt.Errorf("failed to get user profile: %w", err)
 	}
@@ -54,122 +167,511 @@ func (s *spotifyService) GetUserInfo(ctx context.Context, userID string) (string
 	return resp.DisplayName, nil
 }
 
+func (s *spotifyService) CurrentUser(ctx context.Context, client spotify.Client) (*spotify.PrivateUser, error) {
+	var user *spotify.PrivateUser
+	err := s.scheduler.Do(ctx, func() (err error) {
+		user, err = client.CurrentUser()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current user: %w", err)
+	}
+	return user, nil
+}
+
 func (s *spotifyService) GetPlaylistsForUser(ctx context.Context, userID string) ([]*PlaylistInfo, error) {
 	if userID == "" {
 		return nil, errors.New("user ID cannot be empty")
 	}
 
-	resp, err := s.client.GetPlaylistsForUser(userID)
+	limit := SpotifyPlaylistPageLimit
+	result := make([]*PlaylistInfo, 0)
+	for offset := 0; ; {
+		var resp *spotify.SimplePlaylistPage
+		err := s.scheduler.Do(ctx, func() (err error) {
+			resp, err = s.client.GetPlaylistsForUserOpt(userID, &spotify.Options{
+				Limit:  &limit,
+				Offset: &offset,
+			})
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get playlists: %w", err)
+		}
+
+		for _, playlist := range resp.Playlists {
+			result = append(result, toPlaylistInfo(playlist))
+		}
+
+		offset += len(resp.Playlists)
+		if len(resp.Playlists) == 0 || offset >= resp.Total {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+func (s *spotifyService) ListEditablePlaylists(ctx context.Context, client spotify.Client, userID, cursor string, limit int) (*PlaylistPage, error) {
+	if userID == "" {
+		return nil, errors.New("user ID cannot be empty")
+	}
+	if limit <= 0 || limit > SpotifyPlaylistPageLimit {
+		limit = SpotifyPlaylistPageLimit
+	}
+
+	offset, err := decodeCursor(cursor)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get playlists: %w", err)
+		return nil, err
 	}
 
-	result := make([]*PlaylistInfo, 0, len(resp.Playlists))
-	for _, playlist := range resp.Playlists {
-		result = append(result, &PlaylistInfo{
-			Name: playlist.Name,
-			ID:   playlist.ID.String(),
+	// 过滤后一页可能凑不满，继续向后翻 Spotify 的分页，
+	// 游标记录下一条尚未检查的歌单在 Spotify 列表中的位置
+	page := &PlaylistPage{Items: make([]*PlaylistInfo, 0, limit)}
+	pageLimit := SpotifyPlaylistPageLimit
+	for {
+		var resp *spotify.SimplePlaylistPage
+		err := s.scheduler.Do(ctx, func() (err error) {
+			resp, err = client.CurrentUsersPlaylistsOpt(&spotify.Options{
+				Limit:  &pageLimit,
+				Offset: &offset,
+			})
+			return err
 		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get playlists: %w", err)
+		}
+
+		for i, playlist := range resp.Playlists {
+			if playlist.Owner.ID != userID && !playlist.Collaborative {
+				continue
+			}
+			page.Items = append(page.Items, toPlaylistInfo(playlist))
+
+			if len(page.Items) == limit {
+				if next := offset + i + 1; next < resp.Total {
+					page.NextCursor = encodeCursor(next)
+				}
+				return page, nil
+			}
+		}
+
+		offset += len(resp.Playlists)
+		if len(resp.Playlists) == 0 || offset >= resp.Total {
+			return page, nil
+		}
 	}
+}
 
-	return result, nil
+func toPlaylistInfo(playlist spotify.SimplePlaylist) *PlaylistInfo {
+	info := &PlaylistInfo{
+		Name:          playlist.Name,
+		ID:            playlist.ID.String(),
+		URL:           playlist.ExternalURLs["spotify"],
+		TrackCount:    int(playlist.Tracks.Total),
+		OwnerID:       playlist.Owner.ID,
+		OwnerName:     playlist.Owner.DisplayName,
+		Public:        playlist.IsPublic,
+		Collaborative: playlist.Collaborative,
+	}
+	if len(playlist.Images) > 0 {
+		info.ImageURL = playlist.Images[0].URL
+	}
+	return info
 }
 
-func (s *spotifyService) TransferTracksWithUserClient(ctx context.Context, client spotify.Client, playlistID string, tracks []domain.Track) (*domain.TransferResult, error) {
-	if playlistID == "" {
-		return nil, errors.New("playlist ID cannot be empty")
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	offset, err := strconv.Atoi(string(raw))
+	if err != nil || offset < 0 {
+		return 0, ErrInvalidCursor
 	}
+	return offset, nil
+}
 
-	result := &domain.TransferResult{
-		TotalTracks:   len(tracks),
-		SuccessCount:  0,
-		FailedTracks:  make([]domain.FailedTrack, 0),
-		SuccessTracks: make([]string, 0),
+func (s *spotifyService) CreatePlaylist(ctx context.Context, client spotify.Client, userID string, source *domain.MusicList, opts PlaylistOptions) (*PlaylistInfo, error) {
+	if userID == "" {
+		return nil, errors.New("user ID cannot be empty")
+	}
+	if source == nil || strings.TrimSpace(source.Name) == "" {
+		return nil, ErrEmptyPlaylistName
+	}
+	if opts.Collaborative && opts.Public {
+		return nil, ErrCollaborativePublic
 	}
 
-	// 批量处理，消除特殊情况
-	for i := 0; i < len(tracks); i += SpotifyBatchLimit {
-		end := i + SpotifyBatchLimit
-		if end > len(tracks) {
-			end = len(tracks)
+	description := opts.Description
+	if description == "" {
+		description = fmt.Sprintf("从网易云音乐歌单「%s」迁移", source.Name)
+		if source.ID != "" {
+			description += " " + fmt.Sprintf(NeteasePlaylistURLPattern, source.ID)
 		}
+	}
 
-		batchTracks := tracks[i:end]
-		s.processBatch(ctx, client, playlistID, batchTracks, result)
+	var playlist *spotify.FullPlaylist
+	err := s.scheduler.Do(ctx, func() (err error) {
+		if opts.Collaborative {
+			playlist, err = client.CreateCollaborativePlaylistForUser(userID, source.Name, description)
+		} else {
+			playlist, err = client.CreatePlaylistForUser(userID, source.Name, description, opts.Public)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create playlist: %w", err)
 	}
 
-	return result, nil
+	return &PlaylistInfo{
+		Name: playlist.Name,
+		ID:   playlist.ID.String(),
+		URL:  playlist.ExternalURLs["spotify"],
+	}, nil
 }
 
-// processBatch 处理一批歌曲
-func (s *spotifyService) processBatch(ctx context.Context, client spotify.Client, playlistID string, tracks []domain.Track, result *domain.TransferResult) {
-	trackIDs := make([]spotify.ID, 0, len(tracks))
+func (s *spotifyService) TransferTracksWithUserClient(ctx context.Context, client spotify.Client, playlistID string, tracks []domain.Track, opts TransferOptions) (*domain.TransferResult, error) {
+	return s.ContinueTransfer(ctx, client, playlistID, NewTransferResult(tracks), opts)
+}
 
-	for _, track := range tracks {
-		spotifyID, err := s.searchTrack(ctx, track)
-		if err != nil {
-			result.FailedTracks = append(result.FailedTracks, domain.FailedTrack{
-				Track: track,
-				Error: err.Error(),
-			})
+// NewTransferResult 为源歌曲创建初始结果，每首歌曲一条待处理的记录
+func NewTransferResult(tracks []domain.Track) *domain.TransferResult {
+	result := &domain.TransferResult{
+		TotalTracks:    len(tracks),
+		Records:        make([]domain.TrackRecord, len(tracks)),
+		SuccessCount:   0,
+		FailedTracks:   make([]domain.FailedTrack, 0),
+		SuccessTracks:  make([]string, 0),
+		Matches:        make([]domain.MatchedTrack, 0),
+		NeedsReview:    make([]domain.ReviewTrack, 0),
+		AlreadyPresent: make([]domain.MatchedTrack, 0),
+	}
+	for i, track := range tracks {
+		result.Records[i] = domain.TrackRecord{Index: i, Track: track, Status: domain.TrackPending}
+	}
+	return result
+}
+
+// ResetFailed 把失败的歌曲重新放回待处理并移出 FailedTracks，返回重置的数量
+// 添加失败的歌曲保留匹配结果，重试时不再重新搜索
+func ResetFailed(result *domain.TransferResult) int {
+	reset := make(map[int]bool)
+	for i := range result.Records {
+		record := &result.Records[i]
+		if record.Status != domain.TrackFailed {
 			continue
 		}
+		reset[record.Index] = true
+		record.Error = ""
+		if record.SpotifyID != "" {
+			record.Status = domain.TrackMatched
+		} else {
+			record.Status = domain.TrackPending
+		}
+	}
+
+	result.FailedTracks = slices.DeleteFunc(result.FailedTracks, func(f domain.FailedTrack) bool {
+		return reset[f.Index]
+	})
+	return len(reset)
+}
 
-		trackIDs = append(trackIDs, spotifyID)
-		result.SuccessTracks = append(result.SuccessTracks, string(spotifyID))
+func (s *spotifyService) ContinueTransfer(ctx context.Context, client spotify.Client, playlistID string, result *domain.TransferResult, opts TransferOptions) (*domain.TransferResult, error) {
+	if playlistID == "" {
+		return nil, errors.New("playlist ID cannot be empty")
 	}
 
-	if len(trackIDs) == 0 {
-		return
+	// 只处理尚未完成的歌曲，已经有结论的记录保持不变
+	var unfinished []*domain.TrackRecord
+	for i := range result.Records {
+		if status := result.Records[i].Status; status == domain.TrackPending || status == domain.TrackMatched {
+			unfinished = append(unfinished, &result.Records[i])
+		}
 	}
+	if len(unfinished) == 0 {
+		result.Status = domain.TransferCompleted
+		return result, nil
+	}
+	result.Status = domain.TransferRunning
+
+	ctx, waits := ratelimit.TrackWaits(ctx)
+	opts.waits = waits
+	opts.waitedBefore = result.RateLimitWaitMs
+	saved := time.Now()
+	opts.saved = &saved
 
-	// 添加到歌单
-	_, err := client.AddTracksToPlaylist(spotify.ID(playlistID), trackIDs...)
+	// 先读出歌单中已有的歌曲，重复运行同一次迁移不会产生重复
+	existing, err := s.playlistTrackIDs(ctx, client, playlistID)
 	if err != nil {
-		// 如果批量添加失败，将所有歌曲标记为失败
-		for i := range trackIDs {
-			result.FailedTracks = append(result.FailedTracks, domain.FailedTrack{
-				Track: tracks[i],
-				Error: fmt.Sprintf("failed to add to playlist: %s", err.Error()),
-			})
+		if ctx.Err() != nil {
+			result.Status = domain.TransferCancelled
+			return result, nil
 		}
-		return
+		result.Status = domain.TransferFailed
+		return result, err
 	}
 
-	result.SuccessCount += len(trackIDs)
+	// 批量处理，消除特殊情况
+	for i := 0; i < len(unfinished); i += SpotifyBatchLimit {
+		end := min(i+SpotifyBatchLimit, len(unfinished))
+
+		s.processBatch(ctx, client, playlistID, unfinished[i:end], existing, result, opts)
+
+		// 取消后不再开始新的批次，正在添加的这一批已经完成
+		if ctx.Err() != nil {
+			result.Status = domain.TransferCancelled
+		}
+
+		opts.updateWaits(result)
+		opts.checkpoint(result, true)
+		if result.Status == domain.TransferCancelled {
+			return result, nil
+		}
+	}
+
+	result.Status = domain.TransferCompleted
+	return result, nil
 }
 
-// searchTrack 搜索单首歌曲
-func (s *spotifyService) searchTrack(ctx context.Context, track domain.Track) (spotify.ID, error) {
-	// 构建搜索查询，优先使用 artist + title 的组合
-	query := buildSearchQuery(track)
+func (s *spotifyService) PreviewTransfer(ctx context.Context, result *domain.TransferResult, opts TransferOptions) (*domain.TransferResult, error) {
+	var pending []*domain.TrackRecord
+	for i := range result.Records {
+		if result.Records[i].Status == domain.TrackPending {
+			pending = append(pending, &result.Records[i])
+		}
+	}
+	result.Status = domain.TransferRunning
 
-	resp, err := s.client.SearchOpt(query, spotify.SearchTypeTrack, &spotify.Options{
-		Limit: &searchLimit,
-	})
-	if err != nil {
-		return "", fmt.Errorf("search failed for track %s: %w", track.Title, err)
+	ctx, waits := ratelimit.TrackWaits(ctx)
+	opts.waits = waits
+	opts.waitedBefore = result.RateLimitWaitMs
+	saved := time.Now()
+	opts.saved = &saved
+
+	for i := 0; i < len(pending); i += SpotifyBatchLimit {
+		end := min(i+SpotifyBatchLimit, len(pending))
+
+		s.matchRecords(ctx, pending[i:end], result, opts)
+
+		if ctx.Err() != nil {
+			result.Status = domain.TransferCancelled
+		}
+
+		opts.updateWaits(result)
+		opts.checkpoint(result, true)
+		if result.Status == domain.TransferCancelled {
+			return result, nil
+		}
 	}
 
-	if resp.Tracks == nil || len(resp.Tracks.Tracks) == 0 {
-		return "", fmt.Errorf("no results found for track: %s by %s", track.Title, track.Artist)
+	result.Status = domain.TransferPreviewed
+	return result, nil
+}
+
+// snapshotResult 复制一份结果，回调方可以安全持有
+func snapshotResult(result *domain.TransferResult) domain.TransferResult {
+	snapshot := *result
+	snapshot.Records = slices.Clone(result.Records)
+	snapshot.FailedTracks = slices.Clone(result.FailedTracks)
+	snapshot.SuccessTracks = slices.Clone(result.SuccessTracks)
+	snapshot.Matches = slices.Clone(result.Matches)
+	snapshot.NeedsReview = slices.Clone(result.NeedsReview)
+	snapshot.AlreadyPresent = slices.Clone(result.AlreadyPresent)
+	return snapshot
+}
+
+// playlistTrackIDs 分页读出歌单中已有的全部歌曲 ID，本地文件等没有 ID 的条目忽略
+func (s *spotifyService) playlistTrackIDs(ctx context.Context, client spotify.Client, playlistID string) (map[string]bool, error) {
+	ids := make(map[string]bool)
+	limit := SpotifyBatchLimit
+	for offset := 0; ; {
+		var resp *spotify.PlaylistTrackPage
+		err := s.scheduler.Do(ctx, func() (err error) {
+			resp, err = client.GetPlaylistTracksOpt(spotify.ID(playlistID), &spotify.Options{
+				Limit:  &limit,
+				Offset: &offset,
+			}, "items(track(id)),total")
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get playlist tracks: %w", err)
+		}
+
+		for _, item := range resp.Tracks {
+			if item.Track.ID != "" {
+				ids[item.Track.ID.String()] = true
+			}
+		}
+
+		offset += len(resp.Tracks)
+		if len(resp.Tracks) == 0 || offset >= resp.Total {
+			return ids, nil
+		}
+	}
+}
+
+// matchOutcome 一首歌曲的搜索结果
+type matchOutcome struct {
+	match   *MatchResult
+	err     error
+	skipped bool // 迁移被取消，没有搜索或搜索被中断，歌曲保持待处理
+}
+
+// matchBatch 用有限的 worker 并发搜索一批歌曲，结果按原始顺序返回
+func (s *spotifyService) matchBatch(ctx context.Context, records []*domain.TrackRecord, result *domain.TransferResult, opts TransferOptions) []matchOutcome {
+	outcomes := make([]matchOutcome, len(records))
+	sem := make(chan struct{}, s.searchWorkers)
+	var wg sync.WaitGroup
+	var emitMutex sync.Mutex // 搜索阶段不修改 result，只需保证回调串行
+
+	for i, record := range records {
+		wg.Add(1)
+		go func(i int, record domain.TrackRecord) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			// 每次搜索前检查是否已取消
+			if ctx.Err() != nil {
+				outcomes[i].skipped = true
+				return
+			}
+
+			emitMutex.Lock()
+			opts.emit(result, domain.TransferEvent{Type: domain.EventSearching, Index: record.Index, Track: record.Track})
+			emitMutex.Unlock()
+
+			outcomes[i].match, outcomes[i].err = s.matcher.Match(ctx, record.Track, opts.Versions)
+			outcomes[i].skipped = outcomes[i].err != nil && ctx.Err() != nil
+		}(i, *record)
+	}
+	wg.Wait()
+
+	return outcomes
+}
+
+// matchRecords 搜索一批记录中待处理的歌曲，按源歌单顺序记录匹配结果
+func (s *spotifyService) matchRecords(ctx context.Context, records []*domain.TrackRecord, result *domain.TransferResult, opts TransferOptions) {
+	var pending []*domain.TrackRecord
+	for _, record := range records {
+		if record.Status == domain.TrackPending {
+			pending = append(pending, record)
+		}
 	}
+	outcomes := s.matchBatch(ctx, pending, result, opts)
+
+	// 按源歌单顺序汇总，结果与并发执行的先后无关
+	for i, record := range pending {
+		if outcomes[i].skipped {
+			continue
+		}
+		match, err := outcomes[i].match, outcomes[i].err
+		if err != nil {
+			s.failRecord(record, err.Error(), result, opts)
+			continue
+		}
+
+		record.SpotifyID = match.Best.SpotifyID
+		record.Confidence = match.Best.Confidence
+		record.Match = &match.Best
+		record.Strategy = match.Best.Strategy
+
+		// 置信度不足的歌曲不自动添加，交给用户确认
+		if match.NeedsReview {
+			record.Status = domain.TrackNeedsReview
+			result.NeedsReview = append(result.NeedsReview, domain.ReviewTrack{
+				Index:      record.Index,
+				Track:      record.Track,
+				Confidence: match.Best.Confidence,
+				Candidates: match.Candidates,
+			})
+			opts.emit(result, domain.TransferEvent{Type: domain.EventNeedsReview, Index: record.Index, Track: record.Track, SpotifyID: record.SpotifyID, Confidence: record.Confidence})
+			continue
+		}
 
-	return resp.Tracks.Tracks[0].ID, nil
+		record.Status = domain.TrackMatched
+		result.Matches = append(result.Matches, domain.MatchedTrack{
+			Track:      record.Track,
+			SpotifyID:  record.SpotifyID,
+			Confidence: record.Confidence,
+			Strategy:   record.Strategy,
+		})
+		opts.emit(result, domain.TransferEvent{Type: domain.EventMatched, Index: record.Index, Track: record.Track, SpotifyID: record.SpotifyID, Confidence: record.Confidence})
+	}
 }
 
-// buildSearchQuery 构建搜索查询字符串
-// 好品味：将复杂的字符串构建逻辑隔离
-func buildSearchQuery(track domain.Track) string {
-	var parts []string
+// processBatch 处理一批未完成的歌曲，原地更新 result.Records 中每首歌曲的状态
+// 待处理的歌曲先搜索，已匹配的直接添加；existing 是目标歌单中已有的歌曲，添加成功的歌曲也会加入其中
+func (s *spotifyService) processBatch(ctx context.Context, client spotify.Client, playlistID string, records []*domain.TrackRecord, existing map[string]bool, result *domain.TransferResult, opts TransferOptions) {
+	s.matchRecords(ctx, records, result, opts)
+
+	var trackIDs []spotify.ID
+	var adding []*domain.TrackRecord
+	for _, record := range records {
+		if record.Status != domain.TrackMatched {
+			continue
+		}
 
-	if track.Title != "" {
-		parts = append(parts, fmt.Sprintf("track:\"%s\"", track.Title))
+		// 已在歌单中（包括本次迁移中更早的歌曲匹配到同一首），不重复添加
+		if existing[record.SpotifyID] {
+			record.Status = domain.TrackAlreadyPresent
+			result.AlreadyPresent = append(result.AlreadyPresent, domain.MatchedTrack{
+				Track:      record.Track,
+				SpotifyID:  record.SpotifyID,
+				Confidence: record.Confidence,
+				Strategy:   record.Strategy,
+			})
+			opts.emit(result, domain.TransferEvent{Type: domain.EventAlreadyPresent, Index: record.Index, Track: record.Track, SpotifyID: record.SpotifyID, Confidence: record.Confidence})
+			continue
+		}
+
+		existing[record.SpotifyID] = true
+		trackIDs = append(trackIDs, spotify.ID(record.SpotifyID))
+		adding = append(adding, record)
 	}
 
-	if track.Artist != "" {
-		parts = append(parts, fmt.Sprintf("artist:\"%s\"", track.Artist))
+	if len(trackIDs) == 0 {
+		return
 	}
 
-	return strings.Join(parts, " ")
+	// 按源歌单顺序一次性追加，Spotify 中的顺序与源歌单一致
+	// 已经匹配好的这一批即使迁移被取消也要加完
+	err := s.scheduler.Do(context.WithoutCancel(ctx), func() error {
+		_, err := client.AddTracksToPlaylist(spotify.ID(playlistID), trackIDs...)
+		return err
+	})
+
+	// 只有本批中要添加的歌曲受添加结果影响
+	for _, record := range adding {
+		if err != nil {
+			delete(existing, record.SpotifyID)
+			s.failRecord(record, fmt.Sprintf("failed to add to playlist: %s", err.Error()), result, opts)
+			continue
+		}
+
+		record.Status = domain.TrackAdded
+		result.SuccessCount++
+		result.SuccessTracks = append(result.SuccessTracks, record.SpotifyID)
+		opts.emit(result, domain.TransferEvent{Type: domain.EventAdded, Index: record.Index, Track: record.Track, SpotifyID: record.SpotifyID})
+	}
+}
+
+// failRecord 把歌曲标记为失败，并记入 FailedTracks
+func (s *spotifyService) failRecord(record *domain.TrackRecord, reason string, result *domain.TransferResult, opts TransferOptions) {
+	record.Status = domain.TrackFailed
+	record.Error = reason
+	result.FailedTracks = append(result.FailedTracks, domain.FailedTrack{
+		Index: record.Index,
+		Track: record.Track,
+		Error: reason,
+	})
+	opts.emit(result, domain.TransferEvent{Type: domain.EventFailed, Index: record.Index, Track: record.Track, SpotifyID: record.SpotifyID, Error: reason})
 }
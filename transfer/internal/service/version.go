@@ -0,0 +1,61 @@
+package service
+
+import (
+	"regexp"
+	"transfer/internal/domain"
+	"transfer/internal/normalize"
+)
+
+// 版本与期望不符的候选，置信度乘以这个折扣
+// 同一首歌的原版通常能比卡拉 OK 版高出一截，而不会让别的歌排到前面
+const versionPenalty = 0.8
+
+// 版本关键词按优先级排列，一条注释同时命中多个时取靠前的
+// 例如 "Live (2011 Remaster)" 仍是现场版，"Remix Instrumental" 是伴奏
+var versionPatterns = []struct {
+	version domain.VersionType
+	pattern *regexp.Regexp
+}{
+	{domain.VersionKaraoke, regexp.MustCompile(`(?i)karaoke|卡拉\s*ok|\bktv\b|originally performed|in the style of|made famous`)},
+	{domain.VersionInstrumental, regexp.MustCompile(`(?i)instrumental|\binst\.?(?:\s|$)|off[\s-]?vocal|伴奏|纯音乐`)},
+	{domain.VersionSpedUp, regexp.MustCompile(`(?i)sped[\s-]?up|speed[\s-]?up|slowed|nightcore|加速|减速|变速`)},
+	{domain.VersionCover, regexp.MustCompile(`(?i)\bcover\b|翻唱|翻自|原唱`)},
+	{domain.VersionRemix, regexp.MustCompile(`(?i)remix|\bmix\b|\bdj\b|混音`)},
+	{domain.VersionLive, regexp.MustCompile(`(?i)\blive\b|现场|演唱会|ライブ|\bconcert\b`)},
+	{domain.VersionAcoustic, regexp.MustCompile(`(?i)acoustic|unplugged|不插电`)},
+	{domain.VersionRemaster, regexp.MustCompile(`(?i)remaster|重制|修复版`)},
+}
+
+// classifyVersion 根据标题中的括号和 " - " 注释判断版本，没有版本注释时为原版
+// 只看注释不看标题本身，"Live Forever" 这样的歌名不会被当作现场版
+func classifyVersion(title string) domain.VersionType {
+	annotations := normalize.ParseTitle(title).Annotations
+	for _, p := range versionPatterns {
+		for _, annotation := range annotations {
+			if p.pattern.MatchString(annotation) {
+				return p.version
+			}
+		}
+	}
+	return domain.VersionOriginal
+}
+
+// wantedVersion 按偏好确定期望的版本
+func wantedVersion(source domain.VersionType, pref domain.VersionPreference) domain.VersionType {
+	if pref.PreferOriginal {
+		return domain.VersionOriginal
+	}
+	return source
+}
+
+// versionMatches 候选的版本是否符合期望
+func versionMatches(wanted, candidate domain.VersionType, pref domain.VersionPreference) bool {
+	if wanted == candidate {
+		return true
+	}
+	// 允许重制版时，原版和重制版互相替代
+	original := func(v domain.VersionType) bool {
+		return v == domain.VersionOriginal || v == domain.VersionRemaster
+	}
+	return pref.AllowRemaster && original(wanted) && original(candidate)
+}
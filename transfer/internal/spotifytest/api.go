@@ -0,0 +1,197 @@
+// Package spotifytest 内存中的 Spotify Web API，测试时替换真实的网络请求
+package spotifytest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/zmb3/spotify"
+)
+
+// UserID 当前用户接口返回的用户 ID
+const UserID = "tester"
+
+// API 实现 http.RoundTripper，按路径模拟测试用到的几个接口
+// 歌单只保存歌曲 ID，添加的歌曲按顺序追加
+type API struct {
+	// Search 按查询文本返回搜索结果，为 nil 时所有查询都没有结果
+	Search func(query string) []spotify.FullTrack
+	// Intercept 在处理请求之前调用，返回非 nil 时直接作为响应，用来模拟 429、5xx 等
+	Intercept func(req *http.Request) *http.Response
+
+	mutex     sync.Mutex
+	queries   []string
+	playlists map[string][]string
+	tracks    map[string]spotify.FullTrack
+}
+
+func New() *API {
+	return &API{
+		playlists: make(map[string][]string),
+		tracks:    make(map[string]spotify.FullTrack),
+	}
+}
+
+// Client 直接使用 API 的 Spotify 客户端
+func (a *API) Client() spotify.Client {
+	return spotify.NewClient(&http.Client{Transport: a})
+}
+
+// AddTrack 登记一首可以按 ID 读取的歌曲
+func (a *API) AddTrack(track spotify.FullTrack) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.tracks[track.ID.String()] = track
+}
+
+// SetPlaylist 设置歌单中已有的歌曲
+func (a *API) SetPlaylist(id string, trackIDs ...string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.playlists[id] = append([]string(nil), trackIDs...)
+}
+
+// Playlist 歌单中目前的歌曲 ID
+func (a *API) Playlist(id string) []string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return append([]string(nil), a.playlists[id]...)
+}
+
+// Queries 到目前为止收到的搜索查询，按收到的顺序排列
+func (a *API) Queries() []string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return append([]string(nil), a.queries...)
+}
+
+// Track 构造一首候选歌曲
+func Track(id, name, artist string, durationMs int) spotify.FullTrack {
+	return spotify.FullTrack{
+		SimpleTrack: spotify.SimpleTrack{
+			ID:       spotify.ID(id),
+			Name:     name,
+			Artists:  []spotify.SimpleArtist{{Name: artist}},
+			Duration: durationMs,
+		},
+	}
+}
+
+func (a *API) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+	if a.Intercept != nil {
+		if resp := a.Intercept(req); resp != nil {
+			return resp, nil
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	a.serve(rec, req)
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+func (a *API) serve(w http.ResponseWriter, req *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(req.URL.Path, "/v1"), "/")
+	segments := strings.Split(path, "/")
+
+	switch {
+	case req.Method == http.MethodGet && path == "me":
+		writeJSON(w, http.StatusOK, map[string]string{"id": UserID})
+	case req.Method == http.MethodGet && path == "search":
+		a.search(w, req.URL.Query().Get("q"))
+	case req.Method == http.MethodGet && len(segments) == 2 && segments[0] == "tracks":
+		a.track(w, segments[1])
+	case len(segments) == 3 && segments[0] == "playlists" && segments[2] == "tracks":
+		if req.Method == http.MethodPost {
+			a.addTracks(w, req, segments[1])
+		} else {
+			a.playlistTracks(w, segments[1])
+		}
+	default:
+		Error(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (a *API) search(w http.ResponseWriter, query string) {
+	a.mutex.Lock()
+	a.queries = append(a.queries, query)
+	a.mutex.Unlock()
+
+	var found []spotify.FullTrack
+	if a.Search != nil {
+		found = a.Search(query)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tracks": map[string]any{"items": found, "total": len(found)},
+	})
+}
+
+func (a *API) track(w http.ResponseWriter, id string) {
+	a.mutex.Lock()
+	track, ok := a.tracks[id]
+	a.mutex.Unlock()
+
+	if !ok {
+		Error(w, http.StatusNotFound, "non existing id")
+		return
+	}
+	writeJSON(w, http.StatusOK, track)
+}
+
+// playlistTracks 一次返回全部歌曲
+func (a *API) playlistTracks(w http.ResponseWriter, id string) {
+	ids := a.Playlist(id)
+	items := make([]map[string]any, 0, len(ids))
+	for _, trackID := range ids {
+		items = append(items, map[string]any{"track": map[string]string{"id": trackID}})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items, "total": len(items)})
+}
+
+func (a *API) addTracks(w http.ResponseWriter, req *http.Request, id string) {
+	var body struct {
+		URIs []string `json:"uris"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	a.mutex.Lock()
+	for _, uri := range body.URIs {
+		a.playlists[id] = append(a.playlists[id], strings.TrimPrefix(uri, "spotify:track:"))
+	}
+	a.mutex.Unlock()
+
+	writeJSON(w, http.StatusCreated, map[string]string{"snapshot_id": "snapshot"})
+}
+
+// Error 按 Spotify 的格式写入错误响应
+func Error(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{
+		"error": map[string]any{"status": status, "message": message},
+	})
+}
+
+// Respond 构造一个 Spotify 格式的错误响应，供 Intercept 使用
+func Respond(status int, header http.Header) *http.Response {
+	rec := httptest.NewRecorder()
+	for key, values := range header {
+		rec.Header()[key] = values
+	}
+	Error(rec, status, http.StatusText(status))
+	return rec.Result()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
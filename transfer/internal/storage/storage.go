@@ -0,0 +1,120 @@
+// Package storage 以 JSON 文件保存对象，任务和待确认歌曲共用
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Dir 一个目录下每个对象一个 JSON 文件，文件名是对象的 ID
+type Dir[T any] struct {
+	dir   string
+	mutex sync.RWMutex
+}
+
+func NewDir[T any](dir string) (*Dir[T], error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	return &Dir[T]{dir: dir}, nil
+}
+
+func (d *Dir[T]) path(id string) string {
+	return filepath.Join(d.dir, id+".json")
+}
+
+func (d *Dir[T]) Save(id string, v *T) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", id, err)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	// 先写临时文件再 rename，避免进程中断时留下半个文件
+	tmp, err := os.CreateTemp(d.dir, id+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", id, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", id, err)
+	}
+
+	if err := os.Rename(tmp.Name(), d.path(id)); err != nil {
+		return fmt.Errorf("failed to save %s: %w", id, err)
+	}
+	return nil
+}
+
+func (d *Dir[T]) Get(id string) (*T, error) {
+	// 拒绝带路径分隔符的 ID，防止读到目录以外的文件
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return nil, ErrNotFound
+	}
+
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	return d.read(d.path(id))
+}
+
+func (d *Dir[T]) List() ([]*T, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	paths, err := filepath.Glob(filepath.Join(d.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", d.dir, err)
+	}
+
+	items := make([]*T, 0, len(paths))
+	for _, p := range paths {
+		item, err := d.read(p)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (d *Dir[T]) read(path string) (*T, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+
+	var item T
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", filepath.Base(path), err)
+	}
+	return &item, nil
+}
+
+// NewID 随机生成 32 位十六进制 ID，可以直接用作文件名
+func NewID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
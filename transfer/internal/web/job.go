@@ -0,0 +1,284 @@
+package web
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"transfer/internal/domain"
+	"transfer/internal/service/job"
+	"transfer/internal/service/oauth2"
+	"transfer/internal/service/session"
+	"transfer/internal/web/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+var _ handler = (*JobHandler)(nil)
+
+// JobHandler 异步迁移任务
+type JobHandler struct {
+	jobs           *job.Manager
+	tokenManager   oauth2.TokenManager
+	sessionManager session.SessionManager
+	oauthService   oauth2.SpotifyOAuthService
+}
+
+func NewJobHandler(jobs *job.Manager, tokenManager oauth2.TokenManager, sessionManager session.SessionManager, oauthService oauth2.SpotifyOAuthService) *JobHandler {
+	return &JobHandler{
+		jobs:           jobs,
+		tokenManager:   tokenManager,
+		sessionManager: sessionManager,
+		oauthService:   oauthService,
+	}
+}
+
+func (j *JobHandler) RegisterRoutes(server *gin.Engine) {
+	jg := server.Group("/jobs")
+	jg.Use(middleware.RequireSpotifyAuth(j.tokenManager, j.sessionManager, j.oauthService))
+	{
+		jg.POST("", j.CreateJob)
+		jg.GET("/:id", j.GetJob)
+		jg.GET("/:id/events", j.StreamEvents)
+		jg.POST("/:id/resume", j.ResumeJob)
+		jg.POST("/:id/retry", j.RetryFailed)
+		jg.POST("/:id/cancel", j.CancelJob)
+		jg.POST("/:id/commit", j.CommitJob)
+	}
+}
+
+// CreateJob 创建迁移任务，立即返回任务 ID
+func (j *JobHandler) CreateJob(ctx *gin.Context) {
+	var req struct {
+		transferRequest
+		PlaylistID string `json:"playlist_id" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "请求格式错误",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	submitTransfer(ctx, j.jobs, req.PlaylistID, req.transferRequest)
+}
+
+// submitTransfer 按请求创建迁移任务（preview 时为预览任务），立即返回 202 和任务 ID
+func submitTransfer(ctx *gin.Context, jobs *job.Manager, playlistID string, req transferRequest) {
+	submit := jobs.Submit
+	if req.Preview {
+		submit = jobs.SubmitPreview
+	}
+	created, err := submit(ctx.GetString("spotify_user_id"), playlistID, req.domainTracks(), req.Versions)
+	if err != nil {
+		ctx.JSON(submitErrorStatus(err), gin.H{
+			"error":   "failed_to_create_job",
+			"message": "无法创建迁移任务",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{
+		"job_id": created.ID,
+		"status": created.Status,
+	})
+}
+
+// submitErrorStatus 创建任务失败时的状态码，队列已满时客户端可以稍后重试
+func submitErrorStatus(err error) int {
+	if errors.Is(err, job.ErrQueueFull) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// ResumeJob 从第一首未完成的歌曲继续一个已结束的任务
+func (j *JobHandler) ResumeJob(ctx *gin.Context) {
+	j.requeue(ctx, j.jobs.Resume)
+}
+
+// RetryFailed 只重新处理任务中失败的歌曲
+func (j *JobHandler) RetryFailed(ctx *gin.Context) {
+	j.requeue(ctx, j.jobs.RetryFailed)
+}
+
+// CommitJob 按预览任务的计划写入歌单，已匹配的歌曲不再重新搜索
+func (j *JobHandler) CommitJob(ctx *gin.Context) {
+	j.requeue(ctx, j.jobs.Commit)
+}
+
+// CancelJob 取消任务，运行中的任务加完当前批次后停止，最终状态通过查询或 SSE 获取
+func (j *JobHandler) CancelJob(ctx *gin.Context) {
+	found, ok := j.ownedJob(ctx)
+	if !ok {
+		return
+	}
+
+	cancelled, err := j.jobs.Cancel(found.ID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, job.ErrJobFinished) || errors.Is(err, job.ErrNotCancellable) {
+			status = http.StatusConflict
+		}
+		ctx.JSON(status, gin.H{
+			"error":   "failed_to_cancel_job",
+			"message": "无法取消任务",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{
+		"job_id": cancelled.ID,
+		"status": cancelled.Status,
+	})
+}
+
+func (j *JobHandler) requeue(ctx *gin.Context, requeue func(id string) (*job.Job, error)) {
+	found, ok := j.ownedJob(ctx)
+	if !ok {
+		return
+	}
+
+	queued, err := requeue(found.ID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, job.ErrJobNotFinished), errors.Is(err, job.ErrNothingToResume),
+			errors.Is(err, job.ErrNothingToRetry), errors.Is(err, job.ErrNotPreview),
+			errors.Is(err, job.ErrUnfinishedJob):
+			status = http.StatusConflict
+		case errors.Is(err, job.ErrQueueFull):
+			status = http.StatusServiceUnavailable
+		}
+		ctx.JSON(status, gin.H{
+			"error":   "failed_to_requeue_job",
+			"message": "无法重新执行任务",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{
+		"job_id": queued.ID,
+		"status": queued.Status,
+	})
+}
+
+// GetJob 查询任务状态和目前为止的迁移结果
+func (j *JobHandler) GetJob(ctx *gin.Context) {
+	found, ok := j.ownedJob(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, jobView(found))
+}
+
+// StreamEvents 通过 SSE 推送任务的逐首进度，任务结束时发送 done 事件并断开
+func (j *JobHandler) StreamEvents(ctx *gin.Context) {
+	found, ok := j.ownedJob(ctx)
+	if !ok {
+		return
+	}
+
+	events, unsubscribe, err := j.jobs.Subscribe(found.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed_to_subscribe",
+			"message": "无法订阅任务进度",
+			"details": err.Error(),
+		})
+		return
+	}
+	defer unsubscribe()
+
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("X-Accel-Buffering", "no")
+
+	if events == nil {
+		ctx.SSEvent("done", jobView(found))
+		return
+	}
+
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Request.Context().Done():
+			return false
+		case event, open := <-events:
+			if open {
+				ctx.SSEvent(string(event.Type), event)
+				return true
+			}
+		}
+
+		// 任务结束，带上最终状态
+		if latest, err := j.jobs.Get(found.ID); err == nil {
+			found = latest
+		}
+		ctx.SSEvent("done", jobView(found))
+		return false
+	})
+}
+
+// ownedJob 读取路径中的任务，只允许创建者访问
+func (j *JobHandler) ownedJob(ctx *gin.Context) (*job.Job, bool) {
+	found, err := j.jobs.Get(ctx.Param("id"))
+	if errors.Is(err, job.ErrJobNotFound) || (err == nil && found.UserID != ctx.GetString("spotify_user_id")) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "job_not_found",
+			"message": "任务不存在",
+		})
+		return nil, false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed_to_get_job",
+			"message": "无法读取任务",
+			"details": err.Error(),
+		})
+		return nil, false
+	}
+	return found, true
+}
+
+func jobView(j *job.Job) gin.H {
+	view := gin.H{
+		"id":            j.ID,
+		"status":        j.Status,
+		"playlist_id":   j.PlaylistID,
+		"preview":       j.Preview,
+		"total_tracks":  len(j.Tracks),
+		"success_count": 0,
+		"failed_count":  0,
+		"review_count":  0,
+		"present_count": 0,
+		"result":        j.Result,
+		"created_at":    j.CreatedAt,
+		"updated_at":    j.UpdatedAt,
+	}
+	if j.Error != "" {
+		view["error"] = j.Error
+	}
+	if j.Result != nil {
+		view["success_count"] = j.Result.SuccessCount
+		view["failed_count"] = len(j.Result.FailedTracks)
+		view["review_count"] = pendingReviews(j.Result)
+		view["present_count"] = len(j.Result.AlreadyPresent)
+	}
+	return view
+}
+
+// pendingReviews 仍在等待确认的歌曲数量
+// NeedsReview 保留所有进入过确认队列的歌曲，确认或跳过后对应记录的状态会改变，所以按记录计数
+func pendingReviews(result *domain.TransferResult) int {
+	count := 0
+	for _, record := range result.Records {
+		if record.Status == domain.TrackNeedsReview {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,136 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"transfer/internal/service/oauth2"
+	"transfer/internal/service/review"
+	"transfer/internal/service/session"
+	"transfer/internal/web/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zmb3/spotify"
+)
+
+var _ handler = (*ReviewHandler)(nil)
+
+// ReviewHandler 人工确认匹配不确定的歌曲
+type ReviewHandler struct {
+	reviews        *review.Service
+	tokenManager   oauth2.TokenManager
+	sessionManager session.SessionManager
+	oauthService   oauth2.SpotifyOAuthService
+}
+
+func NewReviewHandler(reviews *review.Service, tokenManager oauth2.TokenManager, sessionManager session.SessionManager, oauthService oauth2.SpotifyOAuthService) *ReviewHandler {
+	return &ReviewHandler{
+		reviews:        reviews,
+		tokenManager:   tokenManager,
+		sessionManager: sessionManager,
+		oauthService:   oauthService,
+	}
+}
+
+func (r *ReviewHandler) RegisterRoutes(server *gin.Engine) {
+	rg := server.Group("/spotify/reviews")
+	rg.Use(middleware.RequireSpotifyAuth(r.tokenManager, r.sessionManager, r.oauthService))
+	{
+		rg.GET("", r.List)
+		rg.POST("/:id/approve", r.Approve)
+		rg.POST("/:id/pick", r.Pick)
+		rg.POST("/:id/url", r.PickURL)
+		rg.POST("/:id/skip", r.Skip)
+	}
+}
+
+// List 列出当前用户待确认的歌曲
+func (r *ReviewHandler) List(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, r.reviews.List(ctx.GetString("spotify_user_id")))
+}
+
+// Approve 接受匹配度最高的候选并添加到歌单
+func (r *ReviewHandler) Approve(ctx *gin.Context) {
+	client := ctx.MustGet("spotify_client").(spotify.Client)
+	item, err := r.reviews.Approve(ctx.Request.Context(), client, ctx.GetString("spotify_user_id"), ctx.Param("id"))
+	r.respond(ctx, item, err)
+}
+
+// Pick 选择另一个候选并添加到歌单
+func (r *ReviewHandler) Pick(ctx *gin.Context) {
+	var req struct {
+		SpotifyID string `json:"spotify_id" binding:"required"`
+	}
+	if !bindReviewRequest(ctx, &req) {
+		return
+	}
+
+	client := ctx.MustGet("spotify_client").(spotify.Client)
+	item, err := r.reviews.Pick(ctx.Request.Context(), client, ctx.GetString("spotify_user_id"), ctx.Param("id"), req.SpotifyID)
+	r.respond(ctx, item, err)
+}
+
+// PickURL 使用粘贴的 Spotify 链接并添加到歌单
+func (r *ReviewHandler) PickURL(ctx *gin.Context) {
+	var req struct {
+		URL string `json:"url" binding:"required"`
+	}
+	if !bindReviewRequest(ctx, &req) {
+		return
+	}
+
+	client := ctx.MustGet("spotify_client").(spotify.Client)
+	item, err := r.reviews.PickURL(ctx.Request.Context(), client, ctx.GetString("spotify_user_id"), ctx.Param("id"), req.URL)
+	r.respond(ctx, item, err)
+}
+
+// Skip 跳过这首歌曲
+func (r *ReviewHandler) Skip(ctx *gin.Context) {
+	item, err := r.reviews.Skip(ctx.GetString("spotify_user_id"), ctx.Param("id"))
+	r.respond(ctx, item, err)
+}
+
+func (r *ReviewHandler) respond(ctx *gin.Context, item *review.Item, err error) {
+	switch {
+	case err == nil:
+		ctx.JSON(http.StatusOK, item)
+	case errors.Is(err, review.ErrItemNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "review_item_not_found",
+			"message": "待确认歌曲不存在",
+		})
+	case errors.Is(err, review.ErrAlreadyResolved):
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":   "review_item_resolved",
+			"message": "这首歌曲已经处理过",
+		})
+	case errors.Is(err, review.ErrResolving):
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":   "review_item_resolving",
+			"message": "这首歌曲正在处理中",
+		})
+	case errors.Is(err, review.ErrInvalidChoice), errors.Is(err, review.ErrInvalidURL):
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_choice",
+			"message": "选择的歌曲无效",
+			"details": err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "review_failed",
+			"message": "确认歌曲失败",
+			"details": err.Error(),
+		})
+	}
+}
+
+func bindReviewRequest(ctx *gin.Context, req any) bool {
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "请求格式错误",
+			"details": err.Error(),
+		})
+		return false
+	}
+	return true
+}
@@ -2,9 +2,12 @@
 package web
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"transfer/internal/domain"
 	"transfer/internal/service"
+	"transfer/internal/service/job"
 	"transfer/internal/service/oauth2"
 	"transfer/internal/service/session"
 	"transfer/internal/web/middleware"
@@ -17,14 +20,16 @@ var _ handler = (*SpotifyHandler)(nil)
 
 type SpotifyHandler struct {
 	svc            service.SpotifyService
+	jobs           *job.Manager
 	tokenManager   oauth2.TokenManager
 	sessionManager session.SessionManager
 	oauthService   oauth2.SpotifyOAuthService
 }
 
-func NewSpotifyHandler(svc service.SpotifyService, tokenManager oauth2.TokenManager, sessionManager session.SessionManager, oauthService oauth2.SpotifyOAuthService) *SpotifyHandler {
+func NewSpotifyHandler(svc service.SpotifyService, jobs *job.Manager, tokenManager oauth2.TokenManager, sessionManager session.SessionManager, oauthService oauth2.SpotifyOAuthService) *SpotifyHandler {
 	return &SpotifyHandler{
 		svc:            svc,
+		jobs:           jobs,
 		tokenManager:   tokenManager,
 		sessionManager: sessionManager,
 		oauthService:   oauthService,
@@ -40,6 +45,7 @@ func (s *SpotifyHandler) RegisterRoutes(server *gin.Engine) {
 	{
 		authRequired.GET("/me", s.Me)
 		authRequired.GET("/playlists", s.GetPlaylistsForUser)
+		authRequired.POST("/playlists", s.CreatePlaylist)
 		authRequired.POST("/playlists/:id/tracks", s.AddTracksToPlaylist)
 	}
 }
@@ -55,7 +61,7 @@ func (s *SpotifyHandler) Me(ctx *gin.Context) {
 	spotifyClient := client.(spotify.Client)
 
 	// 获取当前用户信息
-	user, err := spotifyClient.CurrentUser()
+	user, err := s.svc.CurrentUser(ctx.Request.Context(), spotifyClient)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "failed_to_get_user",
@@ -74,6 +80,7 @@ func (s *SpotifyHandler) Me(ctx *gin.Context) {
 	})
 }
 
+// GetPlaylistsForUser 分页返回用户可编辑的歌单，通过 cursor 获取下一页
 func (s *SpotifyHandler) GetPlaylistsForUser(ctx *gin.Context) {
 	// 从中间件获取 Spotify 客户端
 	client, exists := ctx.Get("spotify_client")
@@ -83,9 +90,17 @@ func (s *SpotifyHandler) GetPlaylistsForUser(ctx *gin.Context) {
 	}
 
 	spotifyClient := client.(spotify.Client)
+	limit, _ := strconv.Atoi(ctx.Query("limit"))
 
 	// 获取用户歌单
-	playlists, err := spotifyClient.CurrentUsersPlaylists()
+	page, err := s.svc.ListEditablePlaylists(ctx.Request.Context(), spotifyClient, ctx.GetString("spotify_user_id"), ctx.Query("cursor"), limit)
+	if errors.Is(err, service.ErrInvalidCursor) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_cursor",
+			"message": "分页参数无效",
+		})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "failed_to_get_playlists",
@@ -95,22 +110,18 @@ func (s *SpotifyHandler) GetPlaylistsForUser(ctx *gin.Context) {
 		return
 	}
 
-	// 转换为前端需要的格式
-	result := make([]map[string]interface{}, 0, len(playlists.Playlists))
-	for _, playlist := range playlists.Playlists {
-		result = append(result, map[string]interface{}{
-			"id":   playlist.ID.String(),
-			"name": playlist.Name,
-		})
-	}
-
-	ctx.JSON(http.StatusOK, result)
+	ctx.JSON(http.StatusOK, page)
 }
 
-func (s *SpotifyHandler) AddTracksToPlaylist(ctx *gin.Context) {
-	playlistId := ctx.Param("id")
+// CreatePlaylist 按网易云歌单新建 Spotify 歌单，并创建迁移任务把歌曲导入新歌单
+// 不支持预览：预览任务也需要目标歌单，要先预览请用已有歌单走 POST /jobs
+func (s *SpotifyHandler) CreatePlaylist(ctx *gin.Context) {
 	var req struct {
-		TrackNames []string `json:"track_names"`
+		Source        domain.MusicList         `json:"source"`
+		Public        bool                     `json:"public"`
+		Collaborative bool                     `json:"collaborative"`
+		Description   string                   `json:"description"`
+		Versions      domain.VersionPreference `json:"versions"`
 	}
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
@@ -121,35 +132,63 @@ func (s *SpotifyHandler) AddTracksToPlaylist(ctx *gin.Context) {
 		return
 	}
 
-	// 从中间件获取 Spotify 客户端
-	client, exists := ctx.Get("spotify_client")
-	if !exists {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Spotify client not found"})
-		return
-	}
-
-	spotifyClient := client.(spotify.Client)
+	spotifyClient := ctx.MustGet("spotify_client").(spotify.Client)
+	userID := ctx.GetString("spotify_user_id")
 
-	// 构建歌曲列表
-	var tracks []domain.Track
-	for _, name := range req.TrackNames {
-		tracks = append(tracks, domain.Track{Title: name})
+	playlist, err := s.svc.CreatePlaylist(ctx.Request.Context(), spotifyClient, userID, &req.Source, service.PlaylistOptions{
+		Public:        req.Public,
+		Collaborative: req.Collaborative,
+		Description:   req.Description,
+	})
+	if errors.Is(err, service.ErrEmptyPlaylistName) || errors.Is(err, service.ErrCollaborativePublic) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "歌单参数无效",
+			"details": err.Error(),
+		})
+		return
 	}
-
-	// 这里需要修改 SpotifyService 来接受已授权的客户端
-	// 或者直接在这里处理迁移逻辑
-	result, err := s.svc.TransferTracksWithUserClient(ctx.Request.Context(), spotifyClient, playlistId, tracks)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "transfer_failed",
-			"message": "歌曲迁移失败",
+			"error":   "failed_to_create_playlist",
+			"message": "无法创建歌单",
 			"details": err.Error(),
 		})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"message": "迁移完成",
-		"result":  result,
+	created, err := s.jobs.Submit(userID, playlist.ID, req.Source.Tracks, req.Versions)
+	if err != nil {
+		ctx.JSON(submitErrorStatus(err), gin.H{
+			"error":    "failed_to_create_job",
+			"message":  "歌单已创建，但无法创建迁移任务",
+			"details":  err.Error(),
+			"playlist": playlist,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{
+		"playlist": playlist,
+		"job_id":   created.ID,
+		"status":   created.Status,
 	})
 }
+
+// AddTracksToPlaylist 创建把歌曲迁移到已有歌单的任务，立即返回任务 ID，与 POST /jobs 相同
+func (s *SpotifyHandler) AddTracksToPlaylist(ctx *gin.Context) {
+	playlistId := ctx.Param("id")
+	var req transferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "请求格式错误",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// 大歌单在请求内迁移会超时，交给任务队列，进度通过 /jobs/:id 查询
+	// preview 为 true 时创建预览任务，完成后通过 /jobs/:id/commit 提交
+	submitTransfer(ctx, s.jobs, playlistId, req)
+}
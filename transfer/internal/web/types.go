@@ -1,7 +1,31 @@
 package web
 
-import "github.com/gin-gonic/gin"
+import (
+	"transfer/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
 
 type handler interface {
 	RegisterRoutes(server *gin.Engine)
 }
+
+// transferRequest 迁移请求体
+// tracks 携带完整的歌曲信息；track_names 兼容只传标题的旧客户端
+// preview 为 true 时只匹配不写入，返回的计划可以之后提交
+// versions 是本次迁移对版本的偏好，例如总是优先录音室原版
+type transferRequest struct {
+	Tracks     []domain.Track           `json:"tracks"`
+	TrackNames []string                 `json:"track_names"`
+	Preview    bool                     `json:"preview"`
+	Versions   domain.VersionPreference `json:"versions"`
+}
+
+func (r *transferRequest) domainTracks() []domain.Track {
+	tracks := make([]domain.Track, 0, len(r.Tracks)+len(r.TrackNames))
+	tracks = append(tracks, r.Tracks...)
+	for _, name := range r.TrackNames {
+		tracks = append(tracks, domain.Track{Title: name})
+	}
+	return tracks
+}
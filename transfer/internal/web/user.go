@@ -1,28 +1,30 @@
 package web
 
 import (
-	"fmt"
 	"net/http"
+	"net/url"
 	"time"
+	"transfer/internal/service"
 	"transfer/internal/service/oauth2"
 	"transfer/internal/service/session"
 
-	xoauth2 "golang.org/x/oauth2"
-
 	"github.com/gin-gonic/gin"
-	"github.com/zmb3/spotify"
 )
 
 var _ handler = (*UserHandler)(nil)
 
 type UserHandler struct {
+	frontendURL    string
+	svc            service.SpotifyService
 	oauthService   oauth2.SpotifyOAuthService
 	tokenManager   oauth2.TokenManager
 	sessionManager session.SessionManager
 }
 
-func NewUserHandler(oauthService oauth2.SpotifyOAuthService, tokenManager oauth2.TokenManager, sessionManager session.SessionManager) *UserHandler {
+func NewUserHandler(frontendURL string, svc service.SpotifyService, oauthService oauth2.SpotifyOAuthService, tokenManager oauth2.TokenManager, sessionManager session.SessionManager) *UserHandler {
 	return &UserHandler{
+		frontendURL:    frontendURL,
+		svc:            svc,
 		oauthService:   oauthService,
 		tokenManager:   tokenManager,
 		sessionManager: sessionManager,
@@ -68,45 +70,35 @@ func (u *UserHandler) HandleCallback(c *gin.Context) {
 
 	// 1. 检查是否有错误
 	if error != "" {
-		frontendURL := fmt.Sprintf("http://localhost:3000?auth=error&error=%s", error)
-		c.Redirect(http.StatusFound, frontendURL)
+		u.redirectError(c, error, nil)
 		return
 	}
 
 	// 2. 检查必要参数
 	if code == "" || state == "" {
-		frontendURL := "http://localhost:3000?auth=error&error=missing_parameters"
-		c.Redirect(http.StatusFound, frontendURL)
+		u.redirectError(c, "missing_parameters", nil)
 		return
 	}
 
 	// 3. 验证 state（CSRF 保护）
 	if !u.sessionManager.ValidateState(c, state) {
-		frontendURL := "http://localhost:3000?auth=error&error=invalid_state"
-		c.Redirect(http.StatusFound, frontendURL)
+		u.redirectError(c, "invalid_state", nil)
 		return
 	}
 
 	// 4. 交换授权码获取 token
 	token, err := u.oauthService.HandleCallback(code, state)
 	if err != nil {
-		frontendURL := fmt.Sprintf("http://localhost:3000?auth=error&error=token_exchange_failed&details=%s", err.Error())
-		c.Redirect(http.StatusFound, frontendURL)
+		u.redirectError(c, "token_exchange_failed", err)
 		return
 	}
 
 	// 5. 使用 token 获取用户信息
-	client := spotify.Authenticator{}.NewClient(&xoauth2.Token{
-		AccessToken:  token.AccessToken,
-		RefreshToken: token.RefreshToken,
-		TokenType:    token.TokenType,
-		Expiry:       token.ExpiresAt,
-	})
+	client := u.oauthService.NewClient(token)
 
-	user, err := client.CurrentUser()
+	user, err := u.svc.CurrentUser(c.Request.Context(), client)
 	if err != nil {
-		frontendURL := fmt.Sprintf("http://localhost:3000?auth=error&error=failed_to_get_user&details=%s", err.Error())
-		c.Redirect(http.StatusFound, frontendURL)
+		u.redirectError(c, "failed_to_get_user", err)
 		return
 	}
 
@@ -115,12 +107,18 @@ func (u *UserHandler) HandleCallback(c *gin.Context) {
 	token.UserID = userID
 	err = u.tokenManager.StoreUserToken(userID, token)
 	if err != nil {
-		frontendURL := fmt.Sprintf("http://localhost:3000?auth=error&error=failed_to_store_token&details=%s", err.Error())
-		c.Redirect(http.StatusFound, frontendURL)
+		u.redirectError(c, "failed_to_store_token", err)
+		return
+	}
+
+	// 7. 登录成功后换发会话 ID，防止会话固定攻击
+	err = u.sessionManager.RotateSession(c)
+	if err != nil {
+		u.redirectError(c, "failed_to_set_session", err)
 		return
 	}
 
-	// 7. 设置 session
+	// 8. 设置 session
 	sessionData := &session.SessionData{
 		UserID:    userID,
 		SpotifyID: userID,
@@ -129,14 +127,31 @@ func (u *UserHandler) HandleCallback(c *gin.Context) {
 	}
 	err = u.sessionManager.SetSession(c, sessionData)
 	if err != nil {
-		frontendURL := fmt.Sprintf("http://localhost:3000?auth=error&error=failed_to_set_session&details=%s", err.Error())
-		c.Redirect(http.StatusFound, frontendURL)
+		u.redirectError(c, "failed_to_set_session", err)
 		return
 	}
 
-	// 8. 重定向回前端成功页面
-	frontendURL := fmt.Sprintf("http://localhost:3000?auth=success&user=%s", userID)
-	c.Redirect(http.StatusFound, frontendURL)
+	// 9. 重定向回前端成功页面
+	u.redirect(c, url.Values{"auth": {"success"}, "user": {userID}})
+}
+
+// redirect 带上查询参数重定向回前端
+func (u *UserHandler) redirect(c *gin.Context, params url.Values) {
+	target, err := url.Parse(u.frontendURL)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "invalid frontend URL")
+		return
+	}
+	target.RawQuery = params.Encode()
+	c.Redirect(http.StatusFound, target.String())
+}
+
+func (u *UserHandler) redirectError(c *gin.Context, code string, err error) {
+	params := url.Values{"auth": {"error"}, "error": {code}}
+	if err != nil {
+		params.Set("details", err.Error())
+	}
+	u.redirect(c, params)
 }
 
 // CheckAuthStatus 检查用户授权状态
@@ -154,16 +169,9 @@ func (u *UserHandler) CheckAuthStatus(c *gin.Context) {
 	// 2. 检查 token 是否仍然有效
 	isValid := u.tokenManager.IsTokenValid(sessionData.UserID)
 	if !isValid {
-		// 3. 尝试刷新 token
-		token, err := u.tokenManager.GetUserToken(sessionData.UserID)
-		if err == nil && token.RefreshToken != "" {
-			newToken, err := u.oauthService.RefreshUserToken(token.RefreshToken)
-			if err == nil {
-				newToken.UserID = sessionData.UserID
-				u.tokenManager.StoreUserToken(sessionData.UserID, newToken)
-				isValid = true
-			}
-		}
+		// 3. 尝试刷新 token，与其他请求的刷新互斥
+		_, err := u.oauthService.ValidUserToken(sessionData.UserID)
+		isValid = err == nil
 	}
 
 	// 4. 如果 token 刷新失败，清除 session
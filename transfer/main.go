@@ -1,8 +1,19 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"transfer/internal/config"
+	"transfer/internal/normalize"
 	"transfer/internal/service"
+	"transfer/internal/service/job"
 	"transfer/internal/service/oauth2"
+	"transfer/internal/service/ratelimit"
+	"transfer/internal/service/review"
 	"transfer/internal/service/session"
 	"transfer/internal/web"
 	"transfer/internal/web/middleware"
@@ -12,42 +23,118 @@ import (
 )
 
 func main() {
-	server := initWeb()
-	server.Run(":8081")
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	server := &http.Server{
+		Addr:              cfg.Server.ListenAddr,
+		Handler:           initWeb(cfg),
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+	log.Fatal(server.ListenAndServe())
 }
 
-func initSpotifyClient() spotify.Client {
+func initSpotifyClient(cfg *config.Config, transport http.RoundTripper) spotify.Client {
 	// oauth2 - 保持原有的应用级别客户端（用于公开API调用）
-	return oauth2.NewSpotifyAuth("your-client-id", "your-client-secret")
+	return oauth2.NewSpotifyAuth(cfg.Spotify.ClientID, cfg.Spotify.ClientSecret, transport)
 }
-func initWeb() *gin.Engine {
+
+func initWeb(cfg *config.Config) *gin.Engine {
 	// 1. 初始化组件
-	tokenManager := oauth2.NewMemoryTokenManager()
-	sessionManager := session.NewMemorySessionManager()
+	tokenManager, err := oauth2.NewTokenManager(oauth2.TokenStoreConfig{
+		Backend:       cfg.TokenStore.Backend,
+		Path:          cfg.TokenStore.Path,
+		EncryptionKey: cfg.TokenStore.EncryptionKey,
+	})
+	if err != nil {
+		log.Fatalf("failed to open token store: %v", err)
+	}
+
+	sessionConfig := session.DefaultConfig()
+	sessionConfig.Secret = cfg.SessionSecret()
+	sessionConfig.Dir = cfg.Session.Dir
+	sessionConfig.Secure = cfg.Session.Secure
+	sessionConfig.MaxAge = cfg.Session.MaxAge
+	sessionConfig.IdleTimeout = cfg.Session.IdleTimeout
+	sessionManager, err := session.NewMemorySessionManager(context.Background(), sessionConfig)
+	if err != nil {
+		log.Fatalf("failed to create session manager: %v", err)
+	}
+
+	// 所有 Spotify 调用共享同一个请求预算
+	scheduler := ratelimit.New(ratelimit.Config{
+		RequestsPerSecond: cfg.RateLimit.RequestsPerSecond,
+		Burst:             cfg.RateLimit.Burst,
+		MaxRetries:        cfg.RateLimit.MaxRetries,
+		MaxBackoff:        cfg.RateLimit.MaxBackoff,
+	})
+	transport := scheduler.Transport(http.DefaultTransport)
+
 	oauthService := oauth2.NewSpotifyOAuth(
-		"your-client-id",
-		"your-client-secret",
-		"your-callback-url",
+		cfg.Spotify.ClientID,
+		cfg.Spotify.ClientSecret,
+		cfg.Spotify.RedirectURL,
 		tokenManager,
+		transport,
 	)
 
+	// 日语词典加载较慢，在后台提前加载，不阻塞启动
+	go func() {
+		if err := normalize.LoadJapanese(); err != nil {
+			log.Printf("japanese readings unavailable, falling back to pinyin: %v", err)
+		}
+	}()
+
 	// 2. 初始化服务和处理器
 	nsv := service.NewNeteaseService()
 	neteaseHdl := web.NewNetEaseHandler(nsv)
 
-	ssv := service.NewSpotifyService(initSpotifyClient())
-	spotifyHdl := web.NewSpotifyHandler(ssv, tokenManager, sessionManager, oauthService)
+	appClient := initSpotifyClient(cfg, transport)
+	matcher := service.NewSpotifyMatcher(appClient, scheduler, service.MatcherConfig{
+		CandidateLimit:      cfg.Matcher.CandidateLimit,
+		ReviewThreshold:     cfg.Matcher.ReviewThreshold,
+		DurationToleranceMs: int(cfg.Matcher.DurationTolerance.Milliseconds()),
+	})
+	ssv := service.NewSpotifyService(appClient, matcher, scheduler, cfg.Matcher.SearchWorkers)
+	userHdl := web.NewUserHandler(cfg.Frontend.URL, ssv, oauthService, tokenManager, sessionManager)
 
-	userHdl := web.NewUserHandler(oauthService, tokenManager, sessionManager)
+	jobStore, err := job.NewFileStore(cfg.Jobs.Dir)
+	if err != nil {
+		log.Fatalf("failed to open job store: %v", err)
+	}
+	reviewStore, err := review.NewFileStore(filepath.Join(cfg.Jobs.Dir, "reviews"))
+	if err != nil {
+		log.Fatalf("failed to open review store: %v", err)
+	}
+	reviews, err := review.NewService(reviewStore, scheduler)
+	if err != nil {
+		log.Fatalf("failed to load reviews: %v", err)
+	}
+	jobManager := job.NewManager(jobStore, ssv, reviews, oauthService.GetAuthenticatedClient, cfg.Jobs.Workers)
+	reviews.OnResolved(jobManager.ApplyReview)
+	if err := jobManager.Start(context.Background()); err != nil {
+		log.Fatalf("failed to start job manager: %v", err)
+	}
+	spotifyHdl := web.NewSpotifyHandler(ssv, jobManager, tokenManager, sessionManager, oauthService)
+	jobHdl := web.NewJobHandler(jobManager, tokenManager, sessionManager, oauthService)
+	reviewHdl := web.NewReviewHandler(reviews, tokenManager, sessionManager, oauthService)
 
 	// 3. 配置服务器
 	server := gin.Default()
-	server.Use(middleware.CORSMiddleware())
+	server.Use(middleware.CORSMiddleware(cfg.Frontend.AllowedOrigins))
 
 	// 4. 注册路由
 	neteaseHdl.RegisterRoutes(server)
 	spotifyHdl.RegisterRoutes(server)
 	userHdl.RegisterRoutes(server)
+	jobHdl.RegisterRoutes(server)
+	reviewHdl.RegisterRoutes(server)
 
 	return server
 }